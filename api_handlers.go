@@ -1,19 +1,39 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
+	"sync"
 	"sync/atomic"
 
+	"github.com/flogit2161/Chirpy/internal/auth"
 	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/flogit2161/Chirpy/internal/loginguard"
+	"github.com/flogit2161/Chirpy/internal/mailer"
+	"github.com/flogit2161/Chirpy/internal/moderation"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	db             *database.Queries
-	platform       string
-	jwt            string
-	polka          string
+	fileserverHits      atomic.Int32
+	purgedRefreshTokens atomic.Int64
+	db                  *database.Queries
+	sqlDB               *sql.DB
+	platform            string
+	jwt                 *auth.KeySet
+	polka               string
+	adminKey            string
+	bannedWords         *moderation.WordList
+	fileBannedWords     *moderation.WordList
+	filter              moderation.Filter
+	mailer              mailer.Mailer
+	baseURL             string
+	totpKey             []byte
+	accountGuard        *loginguard.Guard
+	ipGuard             *loginguard.Guard
+	passwordPolicy      auth.PasswordPolicy
+	// background tracks work that outlives its request, shutdown waits for it
+	background sync.WaitGroup
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -25,12 +45,14 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 
 func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request) {
 	count := cfg.fileserverHits.Load()
+	purged := cfg.purgedRefreshTokens.Load()
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(fmt.Sprintf(`<html>
   <body>
     <h1>Welcome, Chirpy Admin</h1>
     <p>Chirpy has been visited %d times!</p>
+    <p>Stale refresh tokens purged: %d</p>
   </body>
-</html>`, count)))
+</html>`, count, purged)))
 }
@@ -1,16 +1,34 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
-	"sort"
 	"strings"
 
 	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/chirptext"
 	"github.com/flogit2161/Chirpy/internal/database"
 	"github.com/google/uuid"
 )
 
+// cleanChirpBody applies the rules shared by new and edited chirps. Only a
+// repost can go without a body of its own.
+func (cfg *apiConfig) cleanChirpBody(body string, isRepost bool) (string, error) {
+	if !isRepost && strings.TrimSpace(body) == "" {
+		return "", fmt.Errorf("Chirp can't be empty")
+	}
+
+	if len(body) > 140 {
+		return "", fmt.Errorf("Chirp is too long")
+	}
+
+	//Filtering body bad words
+	return cfg.filter.Clean(body), nil
+}
+
 func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request) {
 
 	bearerToken, err := auth.GetBearerToken(r.Header)
@@ -19,15 +37,28 @@ func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	validatedUUID, err := auth.ValidateJWT(bearerToken, cfg.jwt)
+	validatedUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	author, err := cfg.db.GetUser(r.Context(), validatedUUID)
 	if err != nil {
 		respondWithError(w, 401, "Could not authenticate user, please log in again")
 		return
 	}
 
+	if !author.EmailVerifiedAt.Valid {
+		respondWithError(w, 403, "Please verify your email before posting chirps")
+		return
+	}
+
 	type BodyJSON struct {
-		Body   string `json:"body"`
-		UserID string `json:"user_id"`
+		Body     string `json:"body"`
+		UserID   string `json:"user_id"`
+		ParentID string `json:"parent_id"`
+		RepostOf string `json:"repost_of"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -41,94 +72,220 @@ func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if len(body.Body) > 140 {
-		respondWithError(w, 400, "Chirp is too long")
+	cleanedBody, err := cfg.cleanChirpBody(body.Body, body.RepostOf != "")
+	if err != nil {
+		respondWithError(w, 400, err.Error())
 		return
 	}
 
-	//Filtering body.Body bad words
-	split := strings.Fields(body.Body)
-	for i, s := range split {
-		lower := strings.ToLower(s)
-		if lower == "kerfuffle" || lower == "sharbert" || lower == "fornax" {
-			split[i] = "****"
+	parentID := uuid.NullUUID{}
+	if body.ParentID != "" {
+		parsedParentID, err := uuid.Parse(body.ParentID)
+		if err != nil {
+			respondWithError(w, 400, "Error parsing parent ID into a UUID")
+			return
 		}
+
+		parent, err := cfg.db.RetrieveChirp(r.Context(), parsedParentID)
+		if err != nil {
+			respondWithError(w, 400, "Parent chirp does not exist")
+			return
+		}
+		parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	// A repost may carry a quote body, which follows the usual chirp rules
+	repostOf := uuid.NullUUID{}
+	if body.RepostOf != "" {
+		parsedOriginalID, err := uuid.Parse(body.RepostOf)
+		if err != nil {
+			respondWithError(w, 400, "Error parsing reposted chirp ID into a UUID")
+			return
+		}
+
+		original, err := cfg.db.RetrieveChirp(r.Context(), parsedOriginalID)
+		if err != nil {
+			respondWithError(w, 400, "Reposted chirp does not exist")
+			return
+		}
+		repostOf = uuid.NullUUID{UUID: original.ID, Valid: true}
 	}
-	cleanedBody := strings.Join(split, " ")
 
 	chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: validatedUUID,
+		Body:     cleanedBody,
+		UserID:   validatedUUID,
+		ParentID: parentID,
+		RepostOf: repostOf,
 	})
+	if err != nil {
+		respondWithError(w, 500, "Error creating chirp")
+		return
+	}
+
+	// The chirp is already saved, a failure below only loses the mentions or tags
+	mentions := chirptext.Mentions(chirp.Body)
+	if len(mentions) > 0 {
+		err = cfg.db.CreateChirpMentions(r.Context(), database.CreateChirpMentionsParams{
+			ChirpID:   chirp.ID,
+			Usernames: mentions,
+		})
+		if err != nil {
+			log.Printf("Could not save mentions of chirp %v, err :%v", chirp.ID, err)
+		}
+	}
+
+	hashtags := chirptext.Hashtags(chirp.Body)
+	if len(hashtags) > 0 {
+		err = cfg.db.CreateChirpHashtags(r.Context(), database.CreateChirpHashtagsParams{
+			Tags:    hashtags,
+			ChirpID: chirp.ID,
+		})
+		if err != nil {
+			log.Printf("Could not save hashtags of chirp %v, err :%v", chirp.ID, err)
+		}
+	}
 
-	jsonChirp := Chirps{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	jsonChirps, err := cfg.chirpsToJSON(r.Context(), []database.Chirp{chirp}, uuid.NullUUID{UUID: validatedUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "Error loading the chirp")
+		return
 	}
 
-	respondWithJSON(w, 201, jsonChirp)
+	respondWithJSON(w, 201, jsonChirps[0])
 
 }
 
 func (cfg *apiConfig) handlerRetrieveAllChirps(w http.ResponseWriter, r *http.Request) {
 
+	viewerID := cfg.optionalViewer(r)
+
 	author_id := r.URL.Query().Get("author_id")
 	sorted := r.URL.Query().Get("sort")
-	jsonChirpsList := []Chirps{}
 
-	if author_id == "" {
-		chirps, err := cfg.db.RetrieveAllChirps(r.Context())
-		if err != nil {
-			respondWithError(w, 400, "Error retrieving the chirps")
-			return
-		}
+	if sorted != "" && sorted != "asc" && sorted != "desc" {
+		respondWithError(w, 400, "Sort must be either asc or desc")
+		return
+	}
 
-		for _, ch := range chirps {
-			jsonChirp := Chirps{
-				ID:        ch.ID,
-				CreatedAt: ch.CreatedAt,
-				UpdatedAt: ch.UpdatedAt,
-				Body:      ch.Body,
-				UserID:    ch.UserID,
-			}
-			jsonChirpsList = append(jsonChirpsList, jsonChirp)
-		}
-	} else {
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.params()
+
+	since, err := parseTimeParam(r.URL.Query().Get("since"))
+	if err != nil {
+		respondWithError(w, 400, "Since must be an RFC3339 timestamp")
+		return
+	}
+
+	until, err := parseTimeParam(r.URL.Query().Get("until"))
+	if err != nil {
+		respondWithError(w, 400, "Until must be an RFC3339 timestamp")
+		return
+	}
+
+	authorID := uuid.NullUUID{}
+	if author_id != "" {
 		parsedAuthorID, err := uuid.Parse(author_id)
 		if err != nil {
 			respondWithError(w, 400, "Error parsing author ID into a UUID")
 			return
 		}
-		usersChirps, err := cfg.db.RetrieveAllChirpsFromUser(r.Context(), parsedAuthorID)
-		if err != nil {
-			respondWithError(w, 400, "Error retrieving user's chirps")
-			return
-		}
+		authorID = uuid.NullUUID{UUID: parsedAuthorID, Valid: true}
+	}
 
-		for _, ch := range usersChirps {
-			jsonChirp := Chirps{
-				ID:        ch.ID,
-				CreatedAt: ch.CreatedAt,
-				UpdatedAt: ch.UpdatedAt,
-				Body:      ch.Body,
-				UserID:    ch.UserID,
-			}
-			jsonChirpsList = append(jsonChirpsList, jsonChirp)
-		}
+	// One extra row tells us whether there is a next page
+	params := database.RetrieveChirpsAscParams{
+		AuthorID:        authorID,
+		Since:           since,
+		Until:           until,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        int32(limit + 1),
 	}
+
+	var chirps []database.Chirp
 	if sorted == "desc" {
-		sort.Slice(jsonChirpsList, func(i, j int) bool {
-			return jsonChirpsList[i].CreatedAt.After(jsonChirpsList[j].CreatedAt)
+		chirps, err = cfg.db.RetrieveChirpsDesc(r.Context(), database.RetrieveChirpsDescParams(params))
+	} else {
+		chirps, err = cfg.db.RetrieveChirpsAsc(r.Context(), params)
+	}
+	if err != nil {
+		respondWithError(w, 400, "Error retrieving the chirps")
+		return
+	}
+
+	page, err := cfg.chirpsPageFromRows(r.Context(), chirps, limit, viewerID)
+	if err != nil {
+		respondWithError(w, 500, "Error loading the chirps")
+		return
+	}
+
+	respondWithJSON(w, 200, page)
+
+}
+
+func (cfg *apiConfig) handlerSearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, 400, "Search query q is required")
+		return
+	}
+
+	viewerID := cfg.optionalViewer(r)
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	results, err := cfg.db.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:    query,
+		RowLimit: int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error searching the chirps")
+		return
+	}
+
+	chirps := []database.Chirp{}
+	for _, ch := range results {
+		chirps = append(chirps, database.Chirp{
+			ID:        ch.ID,
+			CreatedAt: ch.CreatedAt,
+			UpdatedAt: ch.UpdatedAt,
+			Body:      ch.Body,
+			UserID:    ch.UserID,
+			ParentID:  ch.ParentID,
+			RepostOf:  ch.RepostOf,
+			IsRepost:  ch.IsRepost,
 		})
 	}
-	respondWithJSON(w, 200, jsonChirpsList)
 
+	jsonChirps, err := cfg.chirpsToJSON(r.Context(), chirps, viewerID)
+	if err != nil {
+		respondWithError(w, 500, "Error loading the chirps")
+		return
+	}
+	for i := range jsonChirps {
+		jsonChirps[i].Snippet = chirptext.HighlightHTML(results[i].Snippet)
+	}
+
+	respondWithJSON(w, 200, chirpsPage{Chirps: jsonChirps})
 }
 
 func (cfg *apiConfig) handlerRetrieveChirp(w http.ResponseWriter, r *http.Request) {
+	viewerID := cfg.optionalViewer(r)
+
 	chirpID := r.PathValue("chirpID")
 	parsedID, err := uuid.Parse(chirpID)
 	if err != nil {
@@ -142,15 +299,13 @@ func (cfg *apiConfig) handlerRetrieveChirp(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	jsonChirp := Chirps{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	jsonChirps, err := cfg.chirpsToJSON(r.Context(), []database.Chirp{chirp}, viewerID)
+	if err != nil {
+		respondWithError(w, 500, "Error loading the chirp")
+		return
 	}
 
-	respondWithJSON(w, 200, jsonChirp)
+	respondWithJSON(w, 200, jsonChirps[0])
 
 }
 
@@ -161,9 +316,9 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	userUUID, err := auth.ValidateJWT(bearerToken, cfg.jwt)
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
 	if err != nil {
-		respondWithError(w, 401, "Error validating token, token is not valid anymore")
+		respondWithTokenError(w, err)
 		return
 	}
 
@@ -193,3 +348,216 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(204)
 }
+
+func (cfg *apiConfig) handlerUpdateChirp(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	chirpID := r.PathValue("chirpID")
+	parsedID, err := uuid.Parse(chirpID)
+	if err != nil {
+		respondWithError(w, 400, "Error parsing Chirp ID into a UUID")
+		return
+	}
+
+	chirp, err := cfg.db.RetrieveChirp(r.Context(), parsedID)
+	if err != nil {
+		respondWithError(w, 404, "Error trying to load the chirp at this ID")
+		return
+	}
+
+	if chirp.UserID != userUUID {
+		respondWithError(w, 403, "User is not allowed to edit a chirp thats not his")
+		return
+	}
+
+	type BodyJSON struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+
+	defer r.Body.Close()
+	body := BodyJSON{}
+
+	err = decoder.Decode(&body)
+	if err != nil {
+		respondWithError(w, 500, "Error decoding the request")
+		return
+	}
+
+	cleanedBody, err := cfg.cleanChirpBody(body.Body, chirp.IsRepost)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	// The mentions and tags follow the new body, or the old ones would keep listing the chirp
+	tx, err := cfg.sqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Error updating chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	updatedChirp, err := qtx.UpdateChirp(r.Context(), database.UpdateChirpParams{
+		ID:   chirp.ID,
+		Body: cleanedBody,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error updating chirp")
+		return
+	}
+
+	err = replaceChirpTags(r.Context(), qtx, updatedChirp)
+	if err != nil {
+		respondWithError(w, 500, "Error updating the chirp's mentions and hashtags")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Error updating chirp")
+		return
+	}
+
+	jsonChirps, err := cfg.chirpsToJSON(r.Context(), []database.Chirp{updatedChirp}, uuid.NullUUID{UUID: userUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "Error loading the chirp")
+		return
+	}
+
+	respondWithJSON(w, 200, jsonChirps[0])
+}
+
+// replaceChirpTags makes an edited chirp's mentions and hashtags match its body.
+// The ones still in the body keep their row, so an edit doesn't bump a tag's trend.
+func replaceChirpTags(ctx context.Context, qtx *database.Queries, chirp database.Chirp) error {
+	mentions := chirptext.Mentions(chirp.Body)
+	err := qtx.DeleteStaleChirpMentions(ctx, database.DeleteStaleChirpMentionsParams{
+		ChirpID:   chirp.ID,
+		Usernames: mentions,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(mentions) > 0 {
+		err = qtx.CreateChirpMentions(ctx, database.CreateChirpMentionsParams{
+			ChirpID:   chirp.ID,
+			Usernames: mentions,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	hashtags := chirptext.Hashtags(chirp.Body)
+	err = qtx.DeleteStaleChirpHashtags(ctx, database.DeleteStaleChirpHashtagsParams{
+		ChirpID: chirp.ID,
+		Tags:    hashtags,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(hashtags) > 0 {
+		return qtx.CreateChirpHashtags(ctx, database.CreateChirpHashtagsParams{
+			Tags:    hashtags,
+			ChirpID: chirp.ID,
+		})
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerRetrieveChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID := r.PathValue("chirpID")
+	parsedID, err := uuid.Parse(chirpID)
+	if err != nil {
+		respondWithError(w, 400, "Error parsing Chirp ID into a UUID")
+		return
+	}
+
+	_, err = cfg.db.RetrieveChirp(r.Context(), parsedID)
+	if err != nil {
+		respondWithError(w, 404, "Error trying to load the chirp at this ID")
+		return
+	}
+
+	revisions, err := cfg.db.ListChirpRevisions(r.Context(), parsedID)
+	if err != nil {
+		respondWithError(w, 500, "Error retrieving the chirp's revisions")
+		return
+	}
+
+	jsonRevisions := []ChirpRevision{}
+	for _, rev := range revisions {
+		jsonRevisions = append(jsonRevisions, ChirpRevision{
+			ID:        rev.ID,
+			CreatedAt: rev.CreatedAt,
+			ChirpID:   rev.ChirpID,
+			Body:      rev.Body,
+		})
+	}
+
+	respondWithJSON(w, 200, jsonRevisions)
+}
+
+func (cfg *apiConfig) handlerRetrieveReplies(w http.ResponseWriter, r *http.Request) {
+	viewerID := cfg.optionalViewer(r)
+
+	chirpID := r.PathValue("chirpID")
+	parsedID, err := uuid.Parse(chirpID)
+	if err != nil {
+		respondWithError(w, 400, "Error parsing Chirp ID into a UUID")
+		return
+	}
+
+	_, err = cfg.db.RetrieveChirp(r.Context(), parsedID)
+	if err != nil {
+		respondWithError(w, 404, "Error trying to load the chirp at this ID")
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.params()
+
+	replies, err := cfg.db.RetrieveReplies(r.Context(), database.RetrieveRepliesParams{
+		ParentID:        parsedID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error retrieving the replies")
+		return
+	}
+
+	page, err := cfg.chirpsPageFromRows(r.Context(), replies, limit, viewerID)
+	if err != nil {
+		respondWithError(w, 500, "Error loading the replies")
+		return
+	}
+
+	respondWithJSON(w, 200, page)
+}
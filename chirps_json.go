@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+
+	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func databaseChirpToJSON(chirp database.Chirp) Chirps {
+	jsonChirp := Chirps{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+	}
+	if chirp.ParentID.Valid {
+		parentID := chirp.ParentID.UUID
+		jsonChirp.ParentID = &parentID
+	}
+	return jsonChirp
+}
+
+// chirpsToJSON maps chirps to their JSON shape, loading the counters
+// for the whole batch at once instead of one query per chirp. liked_by_me
+// is only filled when a viewer is given.
+func (cfg *apiConfig) chirpsToJSON(ctx context.Context, chirps []database.Chirp, viewerID uuid.NullUUID) ([]Chirps, error) {
+	jsonChirps := make([]Chirps, 0, len(chirps))
+	if len(chirps) == 0 {
+		return jsonChirps, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, ch := range chirps {
+		ids = append(ids, ch.ID)
+	}
+
+	replyCounts, err := cfg.db.CountReplies(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	repliesByChirp := make(map[uuid.UUID]int64, len(replyCounts))
+	for _, count := range replyCounts {
+		repliesByChirp[count.ParentID.UUID] = count.ReplyCount
+	}
+
+	likeCounts, err := cfg.db.CountLikes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	likesByChirp := make(map[uuid.UUID]int64, len(likeCounts))
+	for _, count := range likeCounts {
+		likesByChirp[count.ChirpID] = count.LikeCount
+	}
+
+	originalIDs := []uuid.UUID{}
+	for _, ch := range chirps {
+		if ch.RepostOf.Valid {
+			originalIDs = append(originalIDs, ch.RepostOf.UUID)
+		}
+	}
+	originals := map[uuid.UUID]*ChirpSummary{}
+	if len(originalIDs) > 0 {
+		originalChirps, err := cfg.db.RetrieveChirpsByIDs(ctx, originalIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, original := range originalChirps {
+			originals[original.ID] = &ChirpSummary{
+				ID:        original.ID,
+				CreatedAt: original.CreatedAt,
+				Body:      original.Body,
+				UserID:    original.UserID,
+			}
+		}
+	}
+
+	likedByViewer := map[uuid.UUID]bool{}
+	if viewerID.Valid {
+		liked, err := cfg.db.ListLikedChirps(ctx, database.ListLikedChirpsParams{
+			UserID:   viewerID.UUID,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, chirpID := range liked {
+			likedByViewer[chirpID] = true
+		}
+	}
+
+	for _, ch := range chirps {
+		jsonChirp := databaseChirpToJSON(ch)
+		jsonChirp.ReplyCount = repliesByChirp[ch.ID]
+		jsonChirp.LikeCount = likesByChirp[ch.ID]
+		jsonChirp.LikedByMe = likedByViewer[ch.ID]
+		if ch.RepostOf.Valid {
+			jsonChirp.RepostOf = originals[ch.RepostOf.UUID]
+		}
+		// The original was deleted, the repost stays but shows it is gone
+		jsonChirp.RepostDeleted = ch.IsRepost && jsonChirp.RepostOf == nil
+		jsonChirps = append(jsonChirps, jsonChirp)
+	}
+	return jsonChirps, nil
+}
+
+// chirpsPageFromRows expects up to limit+1 rows from a keyset query; the
+// extra row only signals that another page exists.
+func (cfg *apiConfig) chirpsPageFromRows(ctx context.Context, chirps []database.Chirp, limit int, viewerID uuid.NullUUID) (chirpsPage, error) {
+	page := chirpsPage{}
+	if len(chirps) > limit {
+		chirps = chirps[:limit]
+		last := chirps[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	jsonChirps, err := cfg.chirpsToJSON(ctx, chirps, viewerID)
+	if err != nil {
+		return chirpsPage{}, err
+	}
+	page.Chirps = jsonChirps
+	return page, nil
+}
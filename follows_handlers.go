@@ -0,0 +1,213 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type usersPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Error parsing user ID into a UUID")
+		return
+	}
+
+	if followeeID == userUUID {
+		respondWithError(w, 400, "User can't follow himself")
+		return
+	}
+
+	_, err = cfg.db.GetUser(r.Context(), followeeID)
+	if err != nil {
+		respondWithError(w, 404, "User can't be found")
+		return
+	}
+
+	err = cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userUUID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error following user")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Error parsing user ID into a UUID")
+		return
+	}
+
+	err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userUUID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error unfollowing user")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerListFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, true)
+}
+
+func (cfg *apiConfig) handlerListFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, false)
+}
+
+// listFollows serves both directions of the follow graph, newest follow first.
+func (cfg *apiConfig) listFollows(w http.ResponseWriter, r *http.Request, followers bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Error parsing user ID into a UUID")
+		return
+	}
+
+	_, err = cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "User can't be found")
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.params()
+
+	params := database.ListFollowersParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        int32(limit + 1),
+	}
+
+	var rows []database.ListFollowersRow
+	if followers {
+		rows, err = cfg.db.ListFollowers(r.Context(), params)
+	} else {
+		var following []database.ListFollowingRow
+		following, err = cfg.db.ListFollowing(r.Context(), database.ListFollowingParams(params))
+		for _, row := range following {
+			rows = append(rows, database.ListFollowersRow(row))
+		}
+	}
+	if err != nil {
+		respondWithError(w, 500, "Error retrieving the follow list")
+		return
+	}
+
+	page := usersPage{Users: []User{}}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1]
+		page.NextCursor = encodeCursor(last.FollowedAt, last.ID)
+	}
+
+	for _, row := range rows {
+		page.Users = append(page.Users, databaseUserToPublicJSON(database.User{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			IsChirpyRed: row.IsChirpyRed,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarUrl:   row.AvatarUrl,
+			Username:    row.Username,
+		}))
+	}
+
+	respondWithJSON(w, 200, page)
+}
+
+func (cfg *apiConfig) handlerRetrieveTimeline(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.params()
+
+	chirps, err := cfg.db.RetrieveTimeline(r.Context(), database.RetrieveTimelineParams{
+		UserID:          userUUID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error retrieving the timeline")
+		return
+	}
+
+	page, err := cfg.chirpsPageFromRows(r.Context(), chirps, limit, uuid.NullUUID{UUID: userUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "Error loading the timeline")
+		return
+	}
+
+	respondWithJSON(w, 200, page)
+}
@@ -6,8 +6,53 @@ import (
 	"github.com/alexedwards/argon2id"
 )
 
+// HashParams are the argon2id costs used for new hashes.
+type HashParams struct {
+	// Memory in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// hashParams starts as the library defaults, SetHashParams replaces it at startup.
+var hashParams = &argon2id.Params{
+	Memory:      argon2id.DefaultParams.Memory,
+	Iterations:  argon2id.DefaultParams.Iterations,
+	Parallelism: argon2id.DefaultParams.Parallelism,
+	SaltLength:  argon2id.DefaultParams.SaltLength,
+	KeyLength:   argon2id.DefaultParams.KeyLength,
+}
+
+func DefaultHashParams() HashParams {
+	return HashParams{
+		Memory:      argon2id.DefaultParams.Memory,
+		Iterations:  argon2id.DefaultParams.Iterations,
+		Parallelism: argon2id.DefaultParams.Parallelism,
+	}
+}
+
+// SetHashParams changes the costs of new hashes. It is not safe to call while
+// passwords are being hashed, only when the server starts.
+func SetHashParams(params HashParams) error {
+	if params.Iterations < 1 || params.Parallelism < 1 {
+		return fmt.Errorf("Argon2id iterations and parallelism must be at least 1")
+	}
+	if params.Memory < 8*uint32(params.Parallelism) {
+		return fmt.Errorf("Argon2id memory must be at least 8 KiB per degree of parallelism")
+	}
+
+	hashParams = &argon2id.Params{
+		Memory:      params.Memory,
+		Iterations:  params.Iterations,
+		Parallelism: params.Parallelism,
+		SaltLength:  argon2id.DefaultParams.SaltLength,
+		KeyLength:   argon2id.DefaultParams.KeyLength,
+	}
+	return nil
+}
+
 func HashPassword(password string) (string, error) {
-	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
+	hash, err := argon2id.CreateHash(password, hashParams)
 	if err != nil {
 		return "", fmt.Errorf("Error hashing the password")
 	}
@@ -21,3 +66,16 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	}
 	return match, nil
 }
+
+// NeedsRehash reports whether hash was made with weaker costs than the current
+// ones, so it can be replaced the next time the password is known.
+func NeedsRehash(hash string) (bool, error) {
+	params, _, _, err := argon2id.DecodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	return params.Memory < hashParams.Memory ||
+		params.Iterations < hashParams.Iterations ||
+		params.KeyLength < hashParams.KeyLength, nil
+}
@@ -53,3 +53,50 @@ func TestWrongPWHash(t *testing.T) {
 		t.Errorf("Match returned true, expected to be false with wrong password")
 	}
 }
+
+func TestNeedsRehash(t *testing.T) {
+	defer SetHashParams(DefaultHashParams())
+
+	weak := HashParams{Memory: 8 * 1024, Iterations: 1, Parallelism: 1}
+	err := SetHashParams(weak)
+	if err != nil {
+		t.Fatalf("SetHashParams errored, error :%v", err)
+	}
+
+	hash, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPW function errored, error :%v", err)
+	}
+
+	rehash, err := NeedsRehash(hash)
+	if err != nil || rehash {
+		t.Errorf("Hash made with the current params should not need a rehash, error :%v", err)
+	}
+
+	err = SetHashParams(HashParams{Memory: 16 * 1024, Iterations: 2, Parallelism: 1})
+	if err != nil {
+		t.Fatalf("SetHashParams errored, error :%v", err)
+	}
+
+	rehash, err = NeedsRehash(hash)
+	if err != nil || !rehash {
+		t.Errorf("Hash made with weaker params should need a rehash, error :%v", err)
+	}
+
+	match, err := CheckPasswordHash("password", hash)
+	if err != nil || !match {
+		t.Errorf("Old hash no longer matches after changing params, error :%v", err)
+	}
+}
+
+func TestSetHashParamsInvalid(t *testing.T) {
+	err := SetHashParams(HashParams{Memory: 4, Iterations: 1, Parallelism: 1})
+	if err == nil {
+		t.Errorf("SetHashParams accepted too little memory")
+	}
+
+	err = SetHashParams(HashParams{Memory: 64 * 1024, Iterations: 0, Parallelism: 1})
+	if err == nil {
+		t.Errorf("SetHashParams accepted zero iterations")
+	}
+}
@@ -8,50 +8,61 @@ import (
 	"github.com/google/uuid"
 )
 
+// MakeJWT signs an HS256 token with a shared secret, see KeySet for asymmetric keys.
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	now := time.Now().UTC()
-	expiresAt := now.Add(expiresIn)
-	stringID := userID.String()
-	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		jwt.RegisteredClaims{
-			Issuer:    "chirpy",
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			Subject:   stringID,
-		})
-	signedString, err := token.SignedString([]byte(tokenSecret))
+	ks, err := NewKeySet(NewHMACKey(tokenSecret))
 	if err != nil {
-		return "", fmt.Errorf("Error creating token signature string, err :%v", err)
+		return "", err
 	}
-	return signedString, nil
+	return ks.MakeJWT(userID, expiresIn)
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	ks, err := NewKeySet(NewHMACKey(tokenSecret))
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return ks.ValidateJWT(tokenString)
+}
+
+func (ks *KeySet) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(expiresIn)
+	stringID := userID.String()
+	return ks.signToken(jwt.RegisteredClaims{
+		Issuer:    ks.options.Issuer,
+		Audience:  jwt.ClaimStrings{ks.options.Audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		Subject:   stringID,
+	})
+}
+
+// ValidateJWT returns the token's user, or one of the ErrToken errors when it is refused.
+func (ks *KeySet) ValidateJWT(tokenString string) (uuid.UUID, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&jwt.RegisteredClaims{},
-		func(t *jwt.Token) (interface{}, error) {
-			return []byte(tokenSecret), nil
-		},
+		ks.keyFunc,
+		ks.options.parserOptions()...,
 	)
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("Error parsing token's claims")
+		return uuid.UUID{}, tokenError(err)
 	}
 
 	claims, ok := token.Claims.(*jwt.RegisteredClaims)
 	if !ok {
-		return uuid.UUID{}, fmt.Errorf("Invalid claims type")
+		return uuid.UUID{}, fmt.Errorf("%w: invalid claims type", ErrTokenMalformed)
 	}
 
 	userID, err := claims.GetSubject()
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("Could not access UserID inside of claims")
+		return uuid.UUID{}, fmt.Errorf("%w: could not access UserID inside of claims", ErrTokenMalformed)
 	}
 
 	id, err := uuid.Parse(userID)
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("Error parsing user's id into a UUID")
+		return uuid.UUID{}, fmt.Errorf("%w: error parsing user's id into a UUID", ErrTokenMalformed)
 	}
 
 	return id, nil
@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSABits = 2048
+
+// SigningKey is one JWT key. Keys loaded from a public PEM can only verify tokens.
+type SigningKey struct {
+	// ID is the token's kid header, the RFC 7638 thumbprint for asymmetric keys.
+	// HMAC keys have no ID so tokens issued before kid existed still validate.
+	ID     string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+	public crypto.PublicKey
+}
+
+func (k *SigningKey) Algorithm() string {
+	return k.method.Alg()
+}
+
+func (k *SigningKey) CanSign() bool {
+	return k.sign != nil
+}
+
+// NewHMACKey wraps a shared secret for HS256, which signs and verifies with the same key.
+// An empty secret gives a key that can do neither, anyone could forge its tokens.
+func NewHMACKey(secret string) *SigningKey {
+	if secret == "" {
+		return &SigningKey{method: jwt.SigningMethodHS256}
+	}
+	return &SigningKey{
+		method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+// LoadKeyPEM reads an RSA or Ed25519 key from a PEM file. Private keys
+// (PKCS#8 or PKCS#1) can sign, public keys (PKIX) only verify.
+func LoadKeyPEM(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading key file %s, err :%v", path, err)
+	}
+
+	key, err := ParseKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading key file %s, err :%v", path, err)
+	}
+	return key, nil
+}
+
+func ParseKeyPEM(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing private key, err :%v", err)
+		}
+		return newAsymmetricKey(privateKey)
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing RSA private key, err :%v", err)
+		}
+		return newAsymmetricKey(privateKey)
+	case "PUBLIC KEY":
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing public key, err :%v", err)
+		}
+		return newAsymmetricKey(publicKey)
+	default:
+		return nil, fmt.Errorf("Unsupported PEM block %q", block.Type)
+	}
+}
+
+func newAsymmetricKey(key interface{}) (*SigningKey, error) {
+	k := &SigningKey{}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		k.method, k.sign, k.verify, k.public = jwt.SigningMethodRS256, key, &key.PublicKey, &key.PublicKey
+	case *rsa.PublicKey:
+		k.method, k.verify, k.public = jwt.SigningMethodRS256, key, key
+	case ed25519.PrivateKey:
+		publicKey := key.Public().(ed25519.PublicKey)
+		k.method, k.sign, k.verify, k.public = jwt.SigningMethodEdDSA, key, publicKey, publicKey
+	case ed25519.PublicKey:
+		k.method, k.verify, k.public = jwt.SigningMethodEdDSA, key, key
+	default:
+		return nil, fmt.Errorf("Unsupported key type %T, only RSA and Ed25519 keys are supported", key)
+	}
+
+	if publicKey, ok := k.public.(*rsa.PublicKey); ok && publicKey.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("RSA keys must be at least %d bits", minRSABits)
+	}
+
+	k.ID = k.JWK().Thumbprint()
+	return k, nil
+}
+
+// JWK is the public half of a key as published in a JWKS document.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *SigningKey) JWK() JWK {
+	jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm()}
+
+	switch publicKey := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+	return jwk
+}
+
+// Thumbprint computes the RFC 7638 thumbprint: the SHA-256 of the required
+// members only, in lexicographic order, without whitespace.
+func (jwk JWK) Thumbprint() string {
+	var required interface{}
+	switch jwk.KeyType {
+	case "RSA":
+		required = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	case "OKP":
+		required = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	default:
+		return ""
+	}
+
+	// Base64url members never contain characters json.Marshal would escape
+	encoded, _ := json.Marshal(required)
+	digest := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// KeySet signs tokens with one key and accepts tokens from any of its keys,
+// so a new key can be rolled out while tokens signed by the previous one expire.
+type KeySet struct {
+	signing *SigningKey
+	keys    map[string]*SigningKey
+	ordered []*SigningKey
+	options ValidationOptions
+}
+
+func NewKeySet(signing *SigningKey, verification ...*SigningKey) (*KeySet, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, fmt.Errorf("The signing key must be a private key or a secret")
+	}
+
+	ks := &KeySet{
+		signing: signing,
+		keys:    map[string]*SigningKey{signing.ID: signing},
+		ordered: []*SigningKey{signing},
+		options: DefaultValidationOptions(),
+	}
+	for _, key := range verification {
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("Duplicate key id %q in key set", key.ID)
+		}
+		ks.keys[key.ID] = key
+		ks.ordered = append(ks.ordered, key)
+	}
+	return ks, nil
+}
+
+// WithOptions returns a copy of the key set that issues and validates tokens with opts.
+func (ks *KeySet) WithOptions(opts ValidationOptions) *KeySet {
+	copied := *ks
+	copied.options = opts
+	return &copied
+}
+
+// JWKS lists the public keys of the set, signing key first. Shared secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.ordered {
+		if key.public != nil {
+			jwks.Keys = append(jwks.Keys, key.JWK())
+		}
+	}
+	return jwks
+}
+
+func (ks *KeySet) signToken(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	if ks.signing.ID != "" {
+		token.Header["kid"] = ks.signing.ID
+	}
+
+	signedString, err := token.SignedString(ks.signing.sign)
+	if err != nil {
+		return "", fmt.Errorf("Error creating token signature string, err :%v", err)
+	}
+	return signedString, nil
+}
+
+// keyFunc picks the verification key from the kid header and refuses tokens
+// whose alg doesn't match that key.
+func (ks *KeySet) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTokenUnknownKey, kid)
+	}
+
+	if t.Method.Alg() != key.Algorithm() || !ks.options.allowsAlgorithm(t.Method.Alg()) {
+		return nil, fmt.Errorf("%w: %v", ErrTokenAlgorithm, t.Method.Alg())
+	}
+	return key.verify, nil
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("Could not write PEM file, error :%v", err)
+	}
+	return path
+}
+
+func newEd25519KeyFiles(t *testing.T) (privatePath, publicPath string) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate Ed25519 key, error :%v", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Could not marshal private key, error :%v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Could not marshal public key, error :%v", err)
+	}
+	return writePEM(t, "PRIVATE KEY", privateDER), writePEM(t, "PUBLIC KEY", publicDER)
+}
+
+func TestRS256KeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Could not generate RSA key, error :%v", err)
+	}
+
+	key, err := LoadKeyPEM(writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)))
+	if err != nil {
+		t.Fatalf("LoadKeyPEM errored, error :%v", err)
+	}
+	if key.Algorithm() != "RS256" {
+		t.Errorf("Wrong algorithm. Expected : RS256, Algorithm : %v", key.Algorithm())
+	}
+
+	ks, err := NewKeySet(key)
+	if err != nil {
+		t.Fatalf("NewKeySet errored, error :%v", err)
+	}
+
+	userID := uuid.New()
+	token, err := ks.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT errored, error :%v", err)
+	}
+
+	validate, err := ks.ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("ValidateJWT errored, error :%v", err)
+	}
+	if validate != userID {
+		t.Errorf("Error validating token, UserID : %v ValidatedID : %v", userID, validate)
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldPrivatePath, oldPublicPath := newEd25519KeyFiles(t)
+	newPrivatePath, _ := newEd25519KeyFiles(t)
+
+	oldKey, err := LoadKeyPEM(oldPrivatePath)
+	if err != nil {
+		t.Fatalf("LoadKeyPEM errored, error :%v", err)
+	}
+	oldSet, err := NewKeySet(oldKey)
+	if err != nil {
+		t.Fatalf("NewKeySet errored, error :%v", err)
+	}
+	userID := uuid.New()
+	oldToken, err := oldSet.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT errored, error :%v", err)
+	}
+
+	newKey, err := LoadKeyPEM(newPrivatePath)
+	if err != nil {
+		t.Fatalf("LoadKeyPEM errored, error :%v", err)
+	}
+	oldPublicKey, err := LoadKeyPEM(oldPublicPath)
+	if err != nil {
+		t.Fatalf("LoadKeyPEM errored, error :%v", err)
+	}
+	if oldPublicKey.CanSign() {
+		t.Errorf("A public key should not be able to sign")
+	}
+	if oldPublicKey.ID != oldKey.ID {
+		t.Errorf("Private and public halves have different ids. Private : %v, Public : %v", oldKey.ID, oldPublicKey.ID)
+	}
+
+	rotatedSet, err := NewKeySet(newKey, oldPublicKey)
+	if err != nil {
+		t.Fatalf("NewKeySet errored, error :%v", err)
+	}
+
+	validate, err := rotatedSet.ValidateJWT(oldToken)
+	if err != nil || validate != userID {
+		t.Errorf("Token signed by the previous key was refused, error :%v", err)
+	}
+
+	newToken, err := rotatedSet.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT errored, error :%v", err)
+	}
+	_, err = oldSet.ValidateJWT(newToken)
+	if err == nil {
+		t.Errorf("Old key set validated a token signed by a key it doesn't know")
+	}
+
+	jwks := rotatedSet.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS should list 2 keys, Keys : %v", jwks.Keys)
+	}
+	for _, jwk := range jwks.Keys {
+		if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" || jwk.Algorithm != "EdDSA" || len(jwk.X) == 0 {
+			t.Errorf("Wrong JWK for an Ed25519 key, JWK : %+v", jwk)
+		}
+	}
+}
+
+func TestKeySetRejectsHMACWithPublicKey(t *testing.T) {
+	privatePath, _ := newEd25519KeyFiles(t)
+	key, err := LoadKeyPEM(privatePath)
+	if err != nil {
+		t.Fatalf("LoadKeyPEM errored, error :%v", err)
+	}
+
+	// An HS256 token claiming the asymmetric key's kid must not validate
+	hmacKey := NewHMACKey("secret")
+	hmacKey.ID = key.ID
+	forgedSet, err := NewKeySet(hmacKey)
+	if err != nil {
+		t.Fatalf("NewKeySet errored, error :%v", err)
+	}
+	forged, err := forgedSet.MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT errored, error :%v", err)
+	}
+
+	ks, err := NewKeySet(key)
+	if err != nil {
+		t.Fatalf("NewKeySet errored, error :%v", err)
+	}
+	_, err = ks.ValidateJWT(forged)
+	if err == nil {
+		t.Errorf("ValidateJWT accepted an HS256 token for an EdDSA key")
+	}
+
+	if len(forgedSet.JWKS().Keys) != 0 {
+		t.Errorf("A shared secret was published in the JWKS")
+	}
+}
+
+func TestKeySetRejectsEmptySecret(t *testing.T) {
+	_, err := NewKeySet(NewHMACKey(""))
+	if err == nil {
+		t.Errorf("NewKeySet accepted an empty HMAC secret")
+	}
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// Example from RFC 7638 section 3.1
+	jwk := JWK{
+		KeyType: "RSA",
+		N:       "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:       "AQAB",
+	}
+
+	expected := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"
+	if jwk.Thumbprint() != expected {
+		t.Errorf("Wrong thumbprint. Expected : %v, Thumbprint : %v", expected, jwk.Thumbprint())
+	}
+}
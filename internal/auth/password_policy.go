@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Names of the password rules, returned to clients so they can tell which ones failed.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleEntropy   = "entropy"
+	RuleNotEmail  = "not_email"
+	RuleBreached  = "breached"
+)
+
+const (
+	DefaultPasswordMinLength = 8
+	// Argon2id has no length limit, this only keeps hashing cheap for absurd inputs
+	DefaultPasswordMaxLength  = 256
+	DefaultPasswordMinEntropy = 40
+)
+
+// PasswordPolicy is what a new password has to satisfy.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// MinEntropy in bits, as estimated by PasswordEntropy
+	MinEntropy float64
+	// Breached is optional, nil skips the breached password check
+	Breached *BreachedList
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:  DefaultPasswordMinLength,
+		MaxLength:  DefaultPasswordMaxLength,
+		MinEntropy: DefaultPasswordMinEntropy,
+	}
+}
+
+// PasswordRuleFailure is one rule a password broke.
+type PasswordRuleFailure struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule the password broke, not only the first one.
+type PasswordPolicyError struct {
+	Failures []PasswordRuleFailure
+}
+
+func (e *PasswordPolicyError) Error() string {
+	rules := []string{}
+	for _, failure := range e.Failures {
+		rules = append(rules, failure.Rule)
+	}
+	return fmt.Sprintf("password does not meet the policy: %s", strings.Join(rules, ", "))
+}
+
+// Check returns a *PasswordPolicyError when password breaks any rule, or another
+// error when the breached list can't be read. email is the account's address.
+func (p PasswordPolicy) Check(password, email string) error {
+	failures := []PasswordRuleFailure{}
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		failures = append(failures, PasswordRuleFailure{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("Password must be at least %d characters long", p.MinLength),
+		})
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		failures = append(failures, PasswordRuleFailure{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("Password can't be longer than %d characters", p.MaxLength),
+		})
+	}
+
+	if PasswordEntropy(password) < p.MinEntropy {
+		failures = append(failures, PasswordRuleFailure{
+			Rule:    RuleEntropy,
+			Message: "Password is too easy to guess, make it longer or mix letters, digits and symbols",
+		})
+	}
+
+	if email != "" && passwordMatchesEmail(password, email) {
+		failures = append(failures, PasswordRuleFailure{
+			Rule:    RuleNotEmail,
+			Message: "Password can't be the email address",
+		})
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			failures = append(failures, PasswordRuleFailure{
+				Rule:    RuleBreached,
+				Message: "Password appeared in a data breach, choose another one",
+			})
+		}
+	}
+
+	if len(failures) > 0 {
+		return &PasswordPolicyError{Failures: failures}
+	}
+	return nil
+}
+
+func passwordMatchesEmail(password, email string) bool {
+	password = strings.ToLower(strings.TrimSpace(password))
+	email = strings.ToLower(email)
+	if password == email {
+		return true
+	}
+
+	localPart, _, found := strings.Cut(email, "@")
+	return found && password == localPart
+}
+
+// PasswordEntropy estimates the bits of a password from the character classes it
+// uses. A character repeating the previous one or continuing a run like "abc" or
+// "321" adds nothing, so "aaaaaaaa" scores as one character and "12345678" as two.
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	effectiveLength := 0
+	previous := rune(-1)
+	step := rune(0)
+
+	for _, r := range password {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			lower = true
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			upper = true
+		case r < unicode.MaxASCII && unicode.IsDigit(r):
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+
+		diff := r - previous
+		predictable := previous >= 0 && (diff == 0 || (step != 0 && diff == step))
+		if previous >= 0 && (diff == 1 || diff == -1) {
+			step = diff
+		} else {
+			step = 0
+		}
+		previous = r
+
+		if !predictable {
+			effectiveLength++
+		}
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(effectiveLength) * math.Log2(float64(pool))
+}
+
+// maxBreachedLineLength bounds a "HASH:COUNT" line, the count included.
+const maxBreachedLineLength = 128
+
+// BreachedList looks passwords up in a file of breached password SHA-1 hashes,
+// one "HASH" or "HASH:COUNT" line per hash, sorted by hash like the "ordered by
+// hash" download of Have I Been Pwned. The file is binary searched where it is,
+// so lists with hundreds of millions of hashes need no memory.
+type BreachedList struct {
+	file *os.File
+	size int64
+}
+
+// OpenBreachedList opens a sorted hash file and checks that it starts with a hash.
+func OpenBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening breached passwords file %s, err :%v", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Error reading breached passwords file %s, err :%v", path, err)
+	}
+
+	list := &BreachedList{file: file, size: info.Size()}
+	line, _, _, err := list.lineFrom(0)
+	if err == nil {
+		_, err = parseBreachedLine(line)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Error in breached passwords file %s, err :%v", path, err)
+	}
+	return list, nil
+}
+
+func (l *BreachedList) Close() error {
+	return l.file.Close()
+}
+
+// Contains binary searches the file for the password's hash. Only lines that start
+// in [lo, hi) can still match, each step reads the first line starting after mid.
+func (l *BreachedList) Contains(password string) (bool, error) {
+	digest := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(digest[:]))
+
+	lo, hi := int64(0), l.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, start, next, err := l.lineFrom(mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		hash, err := parseBreachedLine(line)
+		if err != nil {
+			return false, err
+		}
+
+		switch strings.Compare(hash, target) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// lineFrom returns the first line starting at or after offset, where it starts and
+// where the next one starts. start is the file size when no line starts there.
+func (l *BreachedList) lineFrom(offset int64) (string, int64, int64, error) {
+	readAt := offset
+	if offset > 0 {
+		// Reading from the byte before tells whether a line starts right at offset
+		readAt = offset - 1
+	}
+
+	buf := make([]byte, 2*maxBreachedLineLength)
+	n, err := l.file.ReadAt(buf, readAt)
+	if err != nil && err != io.EOF {
+		return "", 0, 0, fmt.Errorf("Error reading breached passwords file, err :%v", err)
+	}
+	buf = buf[:n]
+
+	start := 0
+	if offset > 0 {
+		newline := bytes.IndexByte(buf, '\n')
+		if newline == -1 {
+			if readAt+int64(n) >= l.size {
+				return "", l.size, l.size, nil
+			}
+			return "", 0, 0, fmt.Errorf("Breached passwords file has a line longer than %d bytes", maxBreachedLineLength)
+		}
+		start = newline + 1
+	}
+
+	end := bytes.IndexByte(buf[start:], '\n')
+	next := readAt + int64(n)
+	if end == -1 {
+		if next < l.size {
+			return "", 0, 0, fmt.Errorf("Breached passwords file has a line longer than %d bytes", maxBreachedLineLength)
+		}
+		end = len(buf) - start
+	} else {
+		next = readAt + int64(start+end+1)
+	}
+
+	line := strings.TrimRight(string(buf[start:start+end]), "\r")
+	return line, readAt + int64(start), next, nil
+}
+
+func parseBreachedLine(line string) (string, error) {
+	hash, _, _ := strings.Cut(line, ":")
+	hash = strings.ToUpper(hash)
+	if len(hash) != sha1.Size*2 {
+		return "", fmt.Errorf("%q is not a SHA-1 hash", line)
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", fmt.Errorf("%q is not a SHA-1 hash", line)
+	}
+	return hash, nil
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func failedRules(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Check returned %v, expected a *PasswordPolicyError", err)
+	}
+
+	rules := []string{}
+	for _, failure := range policyErr.Failures {
+		rules = append(rules, failure.Rule)
+	}
+	return rules
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		want     []string
+	}{
+		{"strong", "Tr0ub4dor&3-horse", "walt@example.com", nil},
+		{"empty", "", "walt@example.com", []string{RuleMinLength, RuleEntropy}},
+		{"short", "aB3$", "walt@example.com", []string{RuleMinLength, RuleEntropy}},
+		{"repeated", "aaaaaaaaaaaaaaaa", "walt@example.com", []string{RuleEntropy}},
+		{"sequence", "1234567890123", "walt@example.com", []string{RuleEntropy}},
+		{"email", "Walt.Longmire@Example.com", "walt.longmire@example.com", []string{RuleNotEmail}},
+		{"email local part", "walt.longmire", "walt.longmire@example.com", []string{RuleNotEmail}},
+		{"unknown email", "walt.longmire", "", nil},
+	}
+
+	for _, tt := range tests {
+		got := failedRules(t, policy.Check(tt.password, tt.email))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Check failed rules %v, expected %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPasswordPolicyMaxLength(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	policy.MaxLength = 20
+
+	got := failedRules(t, policy.Check("correct-horse-battery-staple", ""))
+	if !slices.Equal(got, []string{RuleMaxLength}) {
+		t.Errorf("Check failed rules %v, expected only %v", got, RuleMaxLength)
+	}
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	if got := PasswordEntropy(""); got != 0 {
+		t.Errorf("Empty password has %v bits, expected 0", got)
+	}
+
+	if PasswordEntropy("aaaaaaaa") >= PasswordEntropy("a")+1 {
+		t.Errorf("Repeated characters should not add entropy")
+	}
+
+	if PasswordEntropy("abcdefgh") >= PasswordEntropy("ab")+1 {
+		t.Errorf("A run of consecutive characters should not add entropy")
+	}
+
+	if PasswordEntropy("xK9#mQ2!") <= PasswordEntropy("xkqmwzrp") {
+		t.Errorf("Mixing character classes should add entropy")
+	}
+}
+
+func writeBreachedList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("Could not write the breached list, err :%v", err)
+	}
+	return path
+}
+
+func TestBreachedList(t *testing.T) {
+	// Sorted SHA-1 hashes of "password", "123456", "qwerty" lower-cased without
+	// a count and "letmein" in CRLF, the last line has no final newline
+	content := "0000000A1D4B746FAA3FD526FF6D5BC8052FDB38:3\n" +
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n" +
+		"7C4A8D09CA3762AF61E59520943DC26494F8941B:123\n" +
+		"b1b3773a05c0ed0176787a4f1574ff0075f7521e\n" +
+		"B7A875FC1EA228B9061041B7CEC4BD3C52AB3CE3:1200\r\n" +
+		"FFFFFFF8A0382AA9C8D9536EFBA77F261815334D:1"
+
+	list, err := OpenBreachedList(writeBreachedList(t, content))
+	if err != nil {
+		t.Fatalf("OpenBreachedList errored, err :%v", err)
+	}
+	defer list.Close()
+
+	for _, password := range []string{"password", "letmein", "qwerty", "123456"} {
+		breached, err := list.Contains(password)
+		if err != nil {
+			t.Fatalf("Contains errored, err :%v", err)
+		}
+		if !breached {
+			t.Errorf("List should contain %q", password)
+		}
+	}
+
+	breached, err := list.Contains("Tr0ub4dor&3-horse")
+	if err != nil {
+		t.Fatalf("Contains errored, err :%v", err)
+	}
+	if breached {
+		t.Errorf("List should not contain a password that isn't in the file")
+	}
+
+	policy := DefaultPasswordPolicy()
+	policy.MinEntropy = 0
+	policy.Breached = list
+	got := failedRules(t, policy.Check("password", ""))
+	if !slices.Equal(got, []string{RuleBreached}) {
+		t.Errorf("Check failed rules %v, expected only %v", got, RuleBreached)
+	}
+}
+
+func TestOpenBreachedListInvalid(t *testing.T) {
+	_, err := OpenBreachedList(writeBreachedList(t, "not-a-hash\n"))
+	if err == nil {
+		t.Errorf("OpenBreachedList accepted a line that isn't a SHA-1 hash")
+	}
+}
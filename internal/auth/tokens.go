@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -36,3 +37,10 @@ func MakeRefreshToken() (string, error) {
 	encodedString := hex.EncodeToString(byterand)
 	return encodedString, nil
 }
+
+// HashRefreshToken returns the hex SHA-256 digest stored in place of the refresh token.
+// The token already carries 256 random bits, so a plain digest is enough to look it up.
+func HashRefreshToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
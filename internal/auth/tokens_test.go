@@ -63,3 +63,25 @@ func TestMakeRefreshToken(t *testing.T) {
 		t.Errorf("The two hex are equivalent")
 	}
 }
+
+func TestHashRefreshToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken function errored")
+	}
+
+	hash := HashRefreshToken(token)
+	if hash == token || len(hash) != 64 {
+		t.Errorf("Hash should be a 64 hex digest different from the token. Hash : %v", hash)
+	}
+
+	if HashRefreshToken(token) != hash {
+		t.Errorf("Hashing the same token twice gave different digests")
+	}
+
+	// SHA-256 of "abc"
+	expected := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if HashRefreshToken("abc") != expected {
+		t.Errorf("Wrong digest. Expected : %v, Hash : %v", expected, HashRefreshToken("abc"))
+	}
+}
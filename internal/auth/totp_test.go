@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// SHA1 test vectors from RFC 6238 appendix B, truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode errored, error :%v", err)
+		}
+		if code != expected {
+			t.Errorf("Wrong code at %v. Expected : %v, Code : %v", unix, expected, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret errored, error :%v", err)
+	}
+
+	now := time.Now()
+	previous, err := TOTPCode(secret, TOTPStep(now)-1)
+	if err != nil {
+		t.Fatalf("TOTPCode errored, error :%v", err)
+	}
+
+	step, ok := ValidateTOTP(previous, secret, now)
+	if !ok || step != TOTPStep(now)-1 {
+		t.Errorf("Code from the previous period was refused")
+	}
+
+	old, err := TOTPCode(secret, TOTPStep(now)-5)
+	if err != nil {
+		t.Fatalf("TOTPCode errored, error :%v", err)
+	}
+	if _, ok := ValidateTOTP(old, secret, now); ok {
+		t.Errorf("Code from 5 periods ago was accepted")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("JBSWY3DPEHPK3PXP", "Chirpy", "bob@example.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:bob@example.com?") || !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") {
+		t.Errorf("Wrong otpauth URI, URI : %v", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes errored, error :%v", err)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Errorf("Wrong or duplicate recovery code, Code : %v", code)
+		}
+		seen[code] = true
+
+		if NormalizeRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))) != code {
+			t.Errorf("NormalizeRecoveryCode did not give back %v", code)
+		}
+	}
+}
+
+func TestEncryptSecret(t *testing.T) {
+	key, err := ParseEncryptionKey(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("ParseEncryptionKey errored, error :%v", err)
+	}
+
+	encrypted, err := EncryptSecret(key, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("EncryptSecret errored, error :%v", err)
+	}
+	if strings.Contains(encrypted, "JBSWY3DPEHPK3PXP") {
+		t.Errorf("Secret is stored in plaintext")
+	}
+
+	decrypted, err := DecryptSecret(key, encrypted)
+	if err != nil || decrypted != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Error decrypting the secret. Decrypted : %v, error :%v", decrypted, err)
+	}
+
+	otherKey, _ := ParseEncryptionKey(strings.Repeat("cd", 32))
+	if _, err := DecryptSecret(otherKey, encrypted); err == nil {
+		t.Errorf("DecryptSecret succeeded with the wrong key")
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	DefaultIssuer   = "chirpy"
+	DefaultAudience = "chirpy"
+)
+
+// Typed validation errors, so handlers can tell the client why its token was refused.
+var (
+	ErrTokenMalformed        = errors.New("token is malformed")
+	ErrTokenExpired          = errors.New("token has expired")
+	ErrTokenNotYetValid      = errors.New("token is not valid yet")
+	ErrTokenSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenAlgorithm        = errors.New("token signing algorithm is not allowed")
+	ErrTokenUnknownKey       = errors.New("token was signed by an unknown key")
+	ErrTokenIssuer           = errors.New("token has the wrong issuer")
+	ErrTokenAudience         = errors.New("token has the wrong audience")
+	ErrTokenClaimMissing     = errors.New("token is missing a required claim")
+)
+
+// ValidationOptions are the checks applied on top of the signature. The issuer
+// and audience are also the ones written into the tokens the key set signs.
+type ValidationOptions struct {
+	// Algorithms allowed in the alg header, on top of matching the key's own algorithm.
+	// Empty means the algorithms of the keys in the set.
+	Algorithms []string
+	Issuer     string
+	Audience   string
+	// Leeway tolerates clock skew between servers on exp, nbf and iat.
+	Leeway time.Duration
+}
+
+func DefaultValidationOptions() ValidationOptions {
+	return ValidationOptions{
+		Issuer:   DefaultIssuer,
+		Audience: DefaultAudience,
+	}
+}
+
+func (opts ValidationOptions) parserOptions() []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithIssuer(opts.Issuer),
+		jwt.WithAudience(opts.Audience),
+		jwt.WithLeeway(opts.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+}
+
+func (opts ValidationOptions) allowsAlgorithm(alg string) bool {
+	return len(opts.Algorithms) == 0 || slices.Contains(opts.Algorithms, alg)
+}
+
+// tokenError converts the library's errors into the package's typed errors.
+func tokenError(err error) error {
+	switch {
+	case errors.Is(err, ErrTokenAlgorithm):
+		return ErrTokenAlgorithm
+	case errors.Is(err, ErrTokenUnknownKey):
+		return ErrTokenUnknownKey
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotYetValid
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return ErrTokenSignatureInvalid
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenAudience
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return ErrTokenClaimMissing
+	default:
+		return ErrTokenMalformed
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newHMACKeySet(t *testing.T, secret string, opts ValidationOptions) *KeySet {
+	t.Helper()
+	ks, err := NewKeySet(NewHMACKey(secret))
+	if err != nil {
+		t.Fatalf("NewKeySet errored, error :%v", err)
+	}
+	return ks.WithOptions(opts)
+}
+
+func TestValidateJWTTypedErrors(t *testing.T) {
+	defaults := DefaultValidationOptions()
+	otherAudience := DefaultValidationOptions()
+	otherAudience.Audience = "billing"
+	otherIssuer := DefaultValidationOptions()
+	otherIssuer.Issuer = "someone-else"
+	onlyRS256 := DefaultValidationOptions()
+	onlyRS256.Algorithms = []string{"RS256"}
+
+	tests := []struct {
+		name      string
+		signer    *KeySet
+		validator *KeySet
+		expiresIn time.Duration
+		expected  error
+	}{
+		{"expired", newHMACKeySet(t, "secret", defaults), newHMACKeySet(t, "secret", defaults), -time.Minute, ErrTokenExpired},
+		{"bad signature", newHMACKeySet(t, "secret", defaults), newHMACKeySet(t, "other", defaults), time.Hour, ErrTokenSignatureInvalid},
+		{"wrong audience", newHMACKeySet(t, "secret", otherAudience), newHMACKeySet(t, "secret", defaults), time.Hour, ErrTokenAudience},
+		{"wrong issuer", newHMACKeySet(t, "secret", otherIssuer), newHMACKeySet(t, "secret", defaults), time.Hour, ErrTokenIssuer},
+		{"algorithm not allowed", newHMACKeySet(t, "secret", defaults), newHMACKeySet(t, "secret", onlyRS256), time.Hour, ErrTokenAlgorithm},
+	}
+
+	for _, tc := range tests {
+		token, err := tc.signer.MakeJWT(uuid.New(), tc.expiresIn)
+		if err != nil {
+			t.Fatalf("%s: MakeJWT errored, error :%v", tc.name, err)
+		}
+
+		_, err = tc.validator.ValidateJWT(token)
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s: wrong error. Expected : %v, Error : %v", tc.name, tc.expected, err)
+		}
+	}
+}
+
+func TestValidateJWTMalformed(t *testing.T) {
+	ks := newHMACKeySet(t, "secret", DefaultValidationOptions())
+	_, err := ks.ValidateJWT("not.a.token")
+	if !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("Wrong error. Expected : %v, Error : %v", ErrTokenMalformed, err)
+	}
+}
+
+func TestValidateJWTLeeway(t *testing.T) {
+	signer := newHMACKeySet(t, "secret", DefaultValidationOptions())
+	token, err := signer.MakeJWT(uuid.New(), -10*time.Second)
+	if err != nil {
+		t.Fatalf("MakeJWT errored, error :%v", err)
+	}
+
+	opts := DefaultValidationOptions()
+	opts.Leeway = time.Minute
+	_, err = newHMACKeySet(t, "secret", opts).ValidateJWT(token)
+	if err != nil {
+		t.Errorf("Token expired within the leeway was refused, error :%v", err)
+	}
+}
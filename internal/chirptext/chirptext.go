@@ -0,0 +1,132 @@
+package chirptext
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Search snippets come back from Postgres with the matches between these
+// control characters, which are stripped from chirp bodies before highlighting.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 20
+	maxHashtagLength  = 50
+)
+
+// ValidUsername accepts 3 to 20 ASCII letters, digits or underscores.
+func ValidUsername(username string) bool {
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return false
+	}
+	for _, r := range username {
+		if !isUsernameRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Mentions returns the lower-cased usernames tagged with @ in a chirp body,
+// without duplicates and in order of first appearance.
+func Mentions(body string) []string {
+	return extractTokens(body, '@', isUsernameRune, ValidUsername)
+}
+
+// ValidHashtag accepts up to 50 letters, digits or underscores in any script,
+// as long as the tag is not only digits (#1 is not a tag).
+func ValidHashtag(tag string) bool {
+	if tag == "" || utf8.RuneCountInString(tag) > maxHashtagLength {
+		return false
+	}
+
+	onlyDigits := true
+	for _, r := range tag {
+		if !isWordRune(r) {
+			return false
+		}
+		if !unicode.IsDigit(r) {
+			onlyDigits = false
+		}
+	}
+	return !onlyDigits
+}
+
+// Hashtags returns the lower-cased #tags of a chirp body, without duplicates
+// and in order of first appearance.
+func Hashtags(body string) []string {
+	return extractTokens(body, '#', isWordRune, ValidHashtag)
+}
+
+// extractTokens finds words introduced by prefix. The prefix only counts at the
+// start of the body or after a character that can't be part of a token, so
+// emails such as name@example.com are not read as mentions. A token running
+// into letters it can't contain, like @bobé, is dropped rather than cut short.
+func extractTokens(body string, prefix rune, isTokenRune func(rune) bool, valid func(string) bool) []string {
+	tokens := []string{}
+	seen := map[string]bool{}
+
+	previous := rune(-1)
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if r != prefix || (previous != -1 && (isWordRune(previous) || previous == prefix)) {
+			previous = r
+			i += size
+			continue
+		}
+
+		start := i + size
+		end := start
+		for end < len(body) {
+			next, nextSize := utf8.DecodeRuneInString(body[end:])
+			if !isTokenRune(next) {
+				break
+			}
+			end += nextSize
+		}
+
+		previous = r
+		i = end
+		if end > start {
+			last, _ := utf8.DecodeLastRuneInString(body[start:end])
+			previous = last
+		}
+
+		if end < len(body) {
+			next, _ := utf8.DecodeRuneInString(body[end:])
+			if isWordRune(next) {
+				continue
+			}
+		}
+
+		token := strings.ToLower(body[start:end])
+		if valid(token) && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// HighlightHTML escapes a search snippet and only then turns the highlight markers
+// into <mark> tags, so the chirp body itself can never inject markup.
+func HighlightHTML(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, HighlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, HighlightStop, "</mark>")
+}
+
+func isUsernameRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_'
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
@@ -0,0 +1,65 @@
+package chirptext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidUsername(t *testing.T) {
+	valid := []string{"bob", "Alice_99", "abcdefghijklmnopqrst"}
+	for _, username := range valid {
+		if !ValidUsername(username) {
+			t.Errorf("ValidUsername refused %v", username)
+		}
+	}
+
+	invalid := []string{"", "ab", "abcdefghijklmnopqrstu", "bob smith", "bob-smith", "bobé"}
+	for _, username := range invalid {
+		if ValidUsername(username) {
+			t.Errorf("ValidUsername accepted %v", username)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	mentions := Mentions("@Alice hey, did you see @bob's chirp? cc @alice @charlie_1.")
+	expected := []string{"alice", "bob", "charlie_1"}
+	if !reflect.DeepEqual(mentions, expected) {
+		t.Errorf("Error extracting mentions. Expected : %v, Mentions : %v", expected, mentions)
+	}
+}
+
+func TestMentionsIgnoresEmailsAndInvalidNames(t *testing.T) {
+	mentions := Mentions("mail me at bob@example.com, @@alice, @ab or @bobé")
+	if len(mentions) != 0 {
+		t.Errorf("Expected no mentions, Mentions : %v", mentions)
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	tags := Hashtags("Loving #GoLang and #café_culture! #golang again, #1 fan, issue#42")
+	expected := []string{"golang", "café_culture"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Error extracting hashtags. Expected : %v, Hashtags : %v", expected, tags)
+	}
+}
+
+func TestValidHashtag(t *testing.T) {
+	if !ValidHashtag("go2024") {
+		t.Errorf("ValidHashtag refused go2024")
+	}
+	if ValidHashtag("2024") {
+		t.Errorf("ValidHashtag accepted a digits-only tag")
+	}
+	if ValidHashtag("two words") {
+		t.Errorf("ValidHashtag accepted a tag with a space")
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	snippet := "<img src=x onerror=alert(1)> " + HighlightStart + "chirpy" + HighlightStop + " & co"
+	expected := "&lt;img src=x onerror=alert(1)&gt; <mark>chirpy</mark> &amp; co"
+	if got := HighlightHTML(snippet); got != expected {
+		t.Errorf("Error highlighting snippet. Expected : %v, Highlighted : %v", expected, got)
+	}
+}
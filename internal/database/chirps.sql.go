@@ -7,29 +7,75 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countReplies = `-- name: CountReplies :many
+SELECT parent_id, COUNT(*) AS reply_count
+FROM chirps
+WHERE parent_id = ANY($1::uuid[])
+GROUP BY parent_id
+`
+
+type CountRepliesRow struct {
+	ParentID   uuid.NullUUID
+	ReplyCount int64
+}
+
+func (q *Queries) CountReplies(ctx context.Context, chirpIds []uuid.UUID) ([]CountRepliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, countReplies, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRepliesRow
+	for rows.Next() {
+		var i CountRepliesRow
+		if err := rows.Scan(
+			&i.ParentID,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, parent_id, repost_of, is_repost)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $4 IS NOT NULL
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_id, repost_of, is_repost
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body     string
+	UserID   uuid.UUID
+	ParentID uuid.NullUUID
+	RepostOf uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.ParentID, arg.RepostOf)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +83,9 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.RepostOf,
+		&i.IsRepost,
 	)
 	return i, err
 }
@@ -51,13 +100,49 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const retrieveAllChirps = `-- name: RetrieveAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at ASC
+const retrieveChirp = `-- name: RetrieveChirp :one
+SELECT id, created_at, updated_at, body, user_id, parent_id, repost_of, is_repost FROM chirps
+WHERE id = $1
 `
 
-func (q *Queries) RetrieveAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, retrieveAllChirps)
+func (q *Queries) RetrieveChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, retrieveChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RepostOf,
+		&i.IsRepost,
+	)
+	return i, err
+}
+
+const retrieveChirpsAsc = `-- name: RetrieveChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, repost_of, is_repost FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::timestamp IS NULL OR created_at >= $2::timestamp)
+  AND ($3::timestamp IS NULL OR created_at < $3::timestamp)
+  AND ($4::timestamp IS NULL
+   OR (created_at, id) > ($4::timestamp, $5::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $6
+`
+
+type RetrieveChirpsAscParams struct {
+	AuthorID        uuid.NullUUID
+	Since           sql.NullTime
+	Until           sql.NullTime
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) RetrieveChirpsAsc(ctx context.Context, arg RetrieveChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveChirpsAsc, arg.AuthorID, arg.Since, arg.Until, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +156,9 @@ func (q *Queries) RetrieveAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.RepostOf,
+			&i.IsRepost,
 		); err != nil {
 			return nil, err
 		}
@@ -85,14 +173,13 @@ func (q *Queries) RetrieveAllChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const retrieveAllChirpsFromUser = `-- name: RetrieveAllChirpsFromUser :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1
-ORDER BY created_at ASC
+const retrieveChirpsByIDs = `-- name: RetrieveChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, repost_of, is_repost FROM chirps
+WHERE id = ANY($1::uuid[])
 `
 
-func (q *Queries) RetrieveAllChirpsFromUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, retrieveAllChirpsFromUser, userID)
+func (q *Queries) RetrieveChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveChirpsByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -106,6 +193,9 @@ func (q *Queries) RetrieveAllChirpsFromUser(ctx context.Context, userID uuid.UUI
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.RepostOf,
+			&i.IsRepost,
 		); err != nil {
 			return nil, err
 		}
@@ -120,20 +210,164 @@ func (q *Queries) RetrieveAllChirpsFromUser(ctx context.Context, userID uuid.UUI
 	return items, nil
 }
 
-const retrieveChirp = `-- name: RetrieveChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id = $1
+const retrieveChirpsDesc = `-- name: RetrieveChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, repost_of, is_repost FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND ($2::timestamp IS NULL OR created_at >= $2::timestamp)
+  AND ($3::timestamp IS NULL OR created_at < $3::timestamp)
+  AND ($4::timestamp IS NULL
+   OR (created_at, id) < ($4::timestamp, $5::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $6
 `
 
-func (q *Queries) RetrieveChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, retrieveChirp, id)
-	var i Chirp
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Body,
-		&i.UserID,
-	)
-	return i, err
+type RetrieveChirpsDescParams struct {
+	AuthorID        uuid.NullUUID
+	Since           sql.NullTime
+	Until           sql.NullTime
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) RetrieveChirpsDesc(ctx context.Context, arg RetrieveChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveChirpsDesc, arg.AuthorID, arg.Since, arg.Until, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RepostOf,
+			&i.IsRepost,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const retrieveReplies = `-- name: RetrieveReplies :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, repost_of, is_repost FROM chirps
+WHERE parent_id = $1
+  AND ($2::timestamp IS NULL
+   OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type RetrieveRepliesParams struct {
+	ParentID        uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) RetrieveReplies(ctx context.Context, arg RetrieveRepliesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveReplies, arg.ParentID, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RepostOf,
+			&i.IsRepost,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.repost_of, chirps.is_repost,
+    ts_rank(to_tsvector('english', chirps.body), query) AS rank,
+    ts_headline('english', translate(chirps.body, chr(2) || chr(3), ''), query, 'StartSel=' || chr(2) || ', StopSel=' || chr(3)) AS snippet
+FROM chirps, websearch_to_tsquery('english', $1::text) AS query
+WHERE to_tsvector('english', chirps.body) @@ query
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT $2
+`
+
+type SearchChirpsParams struct {
+	Query    string
+	RowLimit int32
+}
+
+type SearchChirpsRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RepostOf  uuid.NullUUID
+	IsRepost  bool
+	Rank      float32
+	Snippet   string
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps, arg.Query, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RepostOf,
+			&i.IsRepost,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
@@ -0,0 +1,134 @@
+package loginguard
+
+import (
+	"context"
+	"time"
+)
+
+// State is the failure history of one key, an account or a client IP.
+type State struct {
+	Failures      int
+	LastFailureAt time.Time
+	// PreviousFailureAt is when the failure before the last one happened, zero if
+	// none. Release puts it back as LastFailureAt.
+	PreviousFailureAt time.Time
+}
+
+// Store keeps failure counts. Reserve must check and record an attempt
+// atomically, so concurrent attempts from several Chirpy instances each see a
+// different count and none slips past a delay another one just started.
+type Store interface {
+	// Reserve records an attempt as a failure at now unless policy still blocks
+	// key, starting over from 1 when the last failure is older than the policy's
+	// window. It returns the key's state and whether the attempt was recorded.
+	Reserve(ctx context.Context, key string, now time.Time, policy Policy) (State, bool, error)
+	// Release takes back one failure, for a reserved attempt that turned out valid.
+	Release(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy decides how long a key waits after its failures.
+type Policy struct {
+	// FreeAttempts failures are allowed before any delay applies.
+	FreeAttempts int
+	// BaseDelay doubles with every failure past FreeAttempts, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutThreshold failures lock the key out for LockoutDuration.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+}
+
+// AccountPolicy protects a single account against password guessing.
+var AccountPolicy = Policy{
+	FreeAttempts:     3,
+	BaseDelay:        1 * time.Second,
+	MaxDelay:         15 * time.Minute,
+	LockoutThreshold: 10,
+	LockoutDuration:  30 * time.Minute,
+	Window:           24 * time.Hour,
+}
+
+// IPPolicy is looser since many users can share one address behind a NAT.
+var IPPolicy = Policy{
+	FreeAttempts:     20,
+	BaseDelay:        1 * time.Second,
+	MaxDelay:         15 * time.Minute,
+	LockoutThreshold: 100,
+	LockoutDuration:  1 * time.Hour,
+	Window:           24 * time.Hour,
+}
+
+type Guard struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func New(store Store, policy Policy) *Guard {
+	return &Guard{store: store, policy: policy, now: time.Now}
+}
+
+// delay is how long a key waits after its failures-th failure.
+func (p Policy) delay(failures int) time.Duration {
+	if failures >= p.LockoutThreshold {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// blockedUntil is when the next attempt is allowed after state's failures. Only
+// attempts that went ahead are failures, so a delay or lockout runs from the
+// failure that started it and refused attempts can't extend it.
+func (p Policy) blockedUntil(state State, now time.Time) time.Time {
+	if state.Failures == 0 || now.Sub(state.LastFailureAt) > p.Window {
+		return time.Time{}
+	}
+	return state.LastFailureAt.Add(p.delay(state.Failures))
+}
+
+// Reservation is the outcome of reserving a login attempt.
+type Reservation struct {
+	// RetryAfter is how long the key must wait, the attempt is refused when it isn't zero.
+	RetryAfter time.Duration
+	// LocksOut reports that the key is locked out if this attempt fails.
+	LocksOut bool
+}
+
+// Reserve counts an attempt against key before the credentials are checked. The
+// check and the count happen in one store call, so a burst of concurrent
+// attempts can't all pass the check before any of them is recorded. Attempts
+// that go ahead stay counted as failures unless they are released, refused
+// ones are not counted at all.
+func (g *Guard) Reserve(ctx context.Context, key string) (Reservation, error) {
+	now := g.now()
+	state, recorded, err := g.store.Reserve(ctx, key, now, g.policy)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	if !recorded {
+		return Reservation{RetryAfter: g.policy.blockedUntil(state, now).Sub(now)}, nil
+	}
+	return Reservation{LocksOut: state.Failures >= g.policy.LockoutThreshold}, nil
+}
+
+// Release gives back an attempt reserved for key once its credentials checked out.
+func (g *Guard) Release(ctx context.Context, key string) error {
+	return g.store.Release(ctx, key)
+}
+
+// Succeed forgets the failures of key.
+func (g *Guard) Succeed(ctx context.Context, key string) error {
+	return g.store.Reset(ctx, key)
+}
@@ -0,0 +1,161 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestGuard(policy Policy) (*Guard, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := New(NewMemoryStore(), policy)
+	g.now = func() time.Time { return now }
+	return g, &now
+}
+
+func TestGuardBackoff(t *testing.T) {
+	ctx := context.Background()
+	g, now := newTestGuard(AccountPolicy)
+
+	// The free attempts, and the one right after them, are never delayed
+	for i := 0; i <= AccountPolicy.FreeAttempts; i++ {
+		reservation, err := g.Reserve(ctx, "account:bob")
+		if err != nil {
+			t.Fatalf("Reserve errored, error :%v", err)
+		}
+		if reservation.RetryAfter != 0 {
+			t.Fatalf("Attempt %d should not be delayed, RetryAfter : %v", i+1, reservation.RetryAfter)
+		}
+	}
+
+	// Refused attempts are not counted, retrying doesn't make the wait longer
+	for i := 0; i < 3; i++ {
+		reservation, _ := g.Reserve(ctx, "account:bob")
+		if reservation.RetryAfter != time.Second {
+			t.Errorf("Wrong backoff. Expected : %v, RetryAfter : %v", time.Second, reservation.RetryAfter)
+		}
+	}
+
+	*now = now.Add(time.Second)
+	reservation, _ := g.Reserve(ctx, "account:bob")
+	if reservation.RetryAfter != 0 {
+		t.Errorf("Attempt after the backoff should go ahead, RetryAfter : %v", reservation.RetryAfter)
+	}
+
+	reservation, _ = g.Reserve(ctx, "account:bob")
+	if reservation.RetryAfter != 2*time.Second {
+		t.Errorf("Wrong backoff. Expected : %v, RetryAfter : %v", 2*time.Second, reservation.RetryAfter)
+	}
+}
+
+func TestGuardConcurrentReserve(t *testing.T) {
+	ctx := context.Background()
+	g, _ := newTestGuard(AccountPolicy)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, err := g.Reserve(ctx, "account:bob")
+			if err != nil {
+				t.Errorf("Reserve errored, error :%v", err)
+				return
+			}
+			if reservation.RetryAfter == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != AccountPolicy.FreeAttempts+1 {
+		t.Errorf("A burst of attempts got %d guesses, expected %d", allowed, AccountPolicy.FreeAttempts+1)
+	}
+}
+
+func TestGuardLockout(t *testing.T) {
+	ctx := context.Background()
+	g, now := newTestGuard(AccountPolicy)
+
+	for i := 1; i <= AccountPolicy.LockoutThreshold; i++ {
+		reservation, err := g.Reserve(ctx, "account:bob")
+		if err != nil {
+			t.Fatalf("Reserve errored, error :%v", err)
+		}
+		if reservation.RetryAfter != 0 {
+			t.Fatalf("Attempt %d was refused, RetryAfter : %v", i, reservation.RetryAfter)
+		}
+		if reservation.LocksOut != (i == AccountPolicy.LockoutThreshold) {
+			t.Errorf("Wrong lockout report after %d failures, LocksOut : %v", i, reservation.LocksOut)
+		}
+		if i < AccountPolicy.LockoutThreshold {
+			*now = now.Add(AccountPolicy.MaxDelay)
+		}
+	}
+
+	// Attempts during the lockout don't push its end back
+	lockedAt := *now
+	for elapsed := time.Duration(0); elapsed < AccountPolicy.LockoutDuration; elapsed += 29 * time.Minute {
+		*now = lockedAt.Add(elapsed)
+		reservation, _ := g.Reserve(ctx, "account:bob")
+		if reservation.RetryAfter != AccountPolicy.LockoutDuration-elapsed {
+			t.Errorf("Wrong lockout. Expected : %v, RetryAfter : %v", AccountPolicy.LockoutDuration-elapsed, reservation.RetryAfter)
+		}
+	}
+
+	*now = lockedAt.Add(AccountPolicy.LockoutDuration)
+	reservation, _ := g.Reserve(ctx, "account:bob")
+	if reservation.RetryAfter != 0 {
+		t.Errorf("Lockout should be over, RetryAfter : %v", reservation.RetryAfter)
+	}
+}
+
+func TestGuardReleaseSucceedAndWindow(t *testing.T) {
+	ctx := context.Background()
+	g, now := newTestGuard(AccountPolicy)
+
+	// Released attempts don't count, however many there are
+	for i := 0; i < AccountPolicy.FreeAttempts+5; i++ {
+		g.Reserve(ctx, "account:carol")
+		g.Release(ctx, "account:carol")
+	}
+	if reservation, _ := g.Reserve(ctx, "account:carol"); reservation.RetryAfter != 0 {
+		t.Errorf("Released attempts were counted, RetryAfter : %v", reservation.RetryAfter)
+	}
+
+	// A released attempt doesn't start a delay either
+	for i := 0; i <= AccountPolicy.FreeAttempts; i++ {
+		g.Reserve(ctx, "account:dave")
+	}
+	*now = now.Add(time.Second)
+	g.Reserve(ctx, "account:dave")
+	g.Release(ctx, "account:dave")
+	if reservation, _ := g.Reserve(ctx, "account:dave"); reservation.RetryAfter != 0 {
+		t.Errorf("Released attempt started a delay, RetryAfter : %v", reservation.RetryAfter)
+	}
+
+	for i := 0; i < AccountPolicy.FreeAttempts+2; i++ {
+		g.Reserve(ctx, "account:bob")
+		g.Reserve(ctx, "account:alice")
+	}
+
+	g.Succeed(ctx, "account:bob")
+	if reservation, _ := g.Reserve(ctx, "account:bob"); reservation.RetryAfter != 0 {
+		t.Errorf("Succeed should clear the failures, RetryAfter : %v", reservation.RetryAfter)
+	}
+	if reservation, _ := g.Reserve(ctx, "account:alice"); reservation.RetryAfter == 0 {
+		t.Errorf("Another key's failures were cleared")
+	}
+
+	// Failures older than the window are forgotten
+	*now = now.Add(AccountPolicy.Window + time.Minute)
+	if reservation, _ := g.Reserve(ctx, "account:alice"); reservation.RetryAfter != 0 {
+		t.Errorf("Old failures were still counted, RetryAfter : %v", reservation.RetryAfter)
+	}
+}
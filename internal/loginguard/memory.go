@@ -0,0 +1,78 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const memoryPruneInterval = time.Minute
+
+// MemoryStore keeps failures in process, for a single instance or for tests.
+type MemoryStore struct {
+	mu       sync.Mutex
+	states   map[string]State
+	prunedAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: map[string]State{}}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key string, now time.Time, policy Policy) (State, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now, policy.Window)
+
+	state := s.states[key]
+	if policy.blockedUntil(state, now).After(now) {
+		return state, false, nil
+	}
+
+	if now.Sub(state.LastFailureAt) > policy.Window {
+		state = State{}
+	}
+	state.Failures++
+	state.PreviousFailureAt = state.LastFailureAt
+	state.LastFailureAt = now
+	s.states[key] = state
+	return state, true, nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[key]
+	if ok && state.Failures > 0 {
+		state.Failures--
+		if !state.PreviousFailureAt.IsZero() {
+			state.LastFailureAt = state.PreviousFailureAt
+		}
+		state.PreviousFailureAt = time.Time{}
+		s.states[key] = state
+	}
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+// prune drops keys whose failures are all forgotten, so the map doesn't grow forever.
+func (s *MemoryStore) prune(now time.Time, window time.Duration) {
+	if now.Sub(s.prunedAt) < memoryPruneInterval {
+		return
+	}
+	s.prunedAt = now
+
+	for key, state := range s.states {
+		if now.Sub(state.LastFailureAt) > window {
+			delete(s.states, key)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package loginguard
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/flogit2161/Chirpy/internal/database"
+)
+
+// PostgresStore shares failure counts between every instance using the database.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Reserve(ctx context.Context, key string, now time.Time, policy Policy) (State, bool, error) {
+	row, err := s.db.ReserveLoginAttempt(ctx, database.ReserveLoginAttemptParams{
+		Key:              key,
+		Now:              now.UTC(),
+		WindowSeconds:    policy.Window.Seconds(),
+		LockoutThreshold: int32(policy.LockoutThreshold),
+		LockoutSeconds:   policy.LockoutDuration.Seconds(),
+		FreeAttempts:     int32(policy.FreeAttempts),
+		BaseDelaySeconds: policy.BaseDelay.Seconds(),
+		MaxDelaySeconds:  policy.MaxDelay.Seconds(),
+	})
+	if err == nil {
+		return stateFromRow(row), true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return State{}, false, err
+	}
+
+	// The update was refused, the key is still blocked
+	row, err = s.db.GetLoginFailures(ctx, key)
+	if err != nil {
+		return State{}, false, err
+	}
+	return stateFromRow(row), false, nil
+}
+
+func stateFromRow(row database.LoginFailure) State {
+	return State{
+		Failures:          int(row.Failures),
+		LastFailureAt:     row.LastFailureAt,
+		PreviousFailureAt: row.PreviousFailureAt.Time,
+	}
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	return s.db.ReleaseLoginFailure(ctx, key)
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.db.ResetLoginFailures(ctx, key)
+}
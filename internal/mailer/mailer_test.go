@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLogMailer(t *testing.T) {
+	out := &bytes.Buffer{}
+	m := NewLogMailer(out)
+
+	err := m.Send(context.Background(), Message{
+		To:      "bob@example.com",
+		Subject: "Verify your email",
+		Body:    "http://localhost:8080/api/users/verify?token=123",
+	})
+	if err != nil {
+		t.Fatalf("LogMailer errored, error :%v", err)
+	}
+
+	logged := out.String()
+	if !strings.Contains(logged, "bob@example.com") || !strings.Contains(logged, "token=123") {
+		t.Errorf("LogMailer did not log the email, Logged : %v", logged)
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	raw := string(buildMessage("chirpy@example.com", Message{
+		To:      "bob@example.com",
+		Subject: "Hello",
+		Body:    "line one\nline two",
+	}))
+
+	if !strings.HasPrefix(raw, "From: chirpy@example.com\r\nTo: bob@example.com\r\nSubject: Hello\r\n") {
+		t.Errorf("Message headers are wrong, Message : %q", raw)
+	}
+
+	if !strings.HasSuffix(raw, "\r\n\r\nline one\r\nline two") {
+		t.Errorf("Message body is wrong, Message : %q", raw)
+	}
+}
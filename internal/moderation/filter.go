@@ -0,0 +1,158 @@
+package moderation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const mask = "****"
+
+// Filter cleans a chirp body before it gets stored.
+type Filter interface {
+	Clean(text string) string
+}
+
+// WordList is a set of banned words that can be changed while the server runs.
+type WordList struct {
+	mu    sync.RWMutex
+	words map[string]struct{}
+}
+
+func NewWordList(words ...string) *WordList {
+	list := &WordList{words: map[string]struct{}{}}
+	for _, word := range words {
+		list.Add(word)
+	}
+	return list
+}
+
+// NormalizeWord lower-cases a word and checks it is a single token the filter can match.
+func NormalizeWord(word string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(word))
+	if normalized == "" {
+		return "", fmt.Errorf("Word is empty")
+	}
+
+	for _, r := range normalized {
+		if !isWordRune(r) {
+			return "", fmt.Errorf("Word can only contain letters and digits")
+		}
+	}
+	return normalized, nil
+}
+
+// Add returns false when the word is invalid or already in the list.
+func (l *WordList) Add(word string) bool {
+	normalized, err := NormalizeWord(word)
+	if err != nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.words[normalized]; ok {
+		return false
+	}
+	l.words[normalized] = struct{}{}
+	return true
+}
+
+// Remove returns false when the word was not in the list.
+func (l *WordList) Remove(word string) bool {
+	normalized := strings.ToLower(strings.TrimSpace(word))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.words[normalized]; !ok {
+		return false
+	}
+	delete(l.words, normalized)
+	return true
+}
+
+// Replace swaps the whole list at once, invalid words are skipped.
+func (l *WordList) Replace(words ...string) {
+	replaced := map[string]struct{}{}
+	for _, word := range words {
+		normalized, err := NormalizeWord(word)
+		if err != nil {
+			continue
+		}
+		replaced[normalized] = struct{}{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.words = replaced
+}
+
+func (l *WordList) Contains(word string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	_, ok := l.words[strings.ToLower(word)]
+	return ok
+}
+
+// Words returns the banned words in alphabetical order.
+func (l *WordList) Words() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	words := make([]string, 0, len(l.words))
+	for word := range l.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// WordFilter masks every word of its list, leaving spacing and punctuation untouched.
+type WordFilter struct {
+	list *WordList
+}
+
+func NewWordFilter(list *WordList) *WordFilter {
+	return &WordFilter{list: list}
+}
+
+func (f *WordFilter) Clean(text string) string {
+	var cleaned strings.Builder
+	cleaned.Grow(len(text))
+
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			cleaned.WriteString(f.maskWord(text[start:i]))
+			start = -1
+		}
+		cleaned.WriteRune(r)
+	}
+	if start != -1 {
+		cleaned.WriteString(f.maskWord(text[start:]))
+	}
+
+	return cleaned.String()
+}
+
+func (f *WordFilter) maskWord(word string) string {
+	if f.list.Contains(word) {
+		return mask
+	}
+	return word
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
@@ -0,0 +1,88 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCleanMasksBannedWords(t *testing.T) {
+	filter := NewWordFilter(NewWordList("kerfuffle", "sharbert", "fornax"))
+
+	cleaned := filter.Clean("This is a Kerfuffle opinion I need to share with the world")
+	expected := "This is a **** opinion I need to share with the world"
+	if cleaned != expected {
+		t.Errorf("Error cleaning body. Expected : %v, Cleaned : %v", expected, cleaned)
+	}
+}
+
+func TestCleanKeepsPunctuationAndSpacing(t *testing.T) {
+	filter := NewWordFilter(NewWordList("kerfuffle"))
+
+	cleaned := filter.Clean("What a  kerfuffle!\tReally, KERFUFFLE.")
+	expected := "What a  ****!\tReally, ****."
+	if cleaned != expected {
+		t.Errorf("Error cleaning body. Expected : %q, Cleaned : %q", expected, cleaned)
+	}
+}
+
+func TestCleanUnicode(t *testing.T) {
+	filter := NewWordFilter(NewWordList("ÉCLAIR"))
+
+	cleaned := filter.Clean("un éclair, deux Éclairs")
+	expected := "un ****, deux Éclairs"
+	if cleaned != expected {
+		t.Errorf("Error cleaning body. Expected : %q, Cleaned : %q", expected, cleaned)
+	}
+}
+
+func TestWordListAddRemove(t *testing.T) {
+	list := NewWordList()
+
+	if !list.Add(" Fornax ") {
+		t.Fatalf("Add refused a valid word")
+	}
+	if list.Add("fornax") {
+		t.Errorf("Add accepted a duplicate word")
+	}
+	if list.Add("two words") {
+		t.Errorf("Add accepted a word with a space")
+	}
+	if !list.Contains("FORNAX") {
+		t.Errorf("Contains did not find an added word")
+	}
+	if !list.Remove("fornax") {
+		t.Errorf("Remove did not find an added word")
+	}
+	if len(list.Words()) != 0 {
+		t.Errorf("Word list should be empty, Words : %v", list.Words())
+	}
+}
+
+func TestWordListReplace(t *testing.T) {
+	list := NewWordList("kerfuffle", "sharbert")
+
+	list.Replace("Fornax", "sharbert", "two words")
+	expected := []string{"fornax", "sharbert"}
+	if !reflect.DeepEqual(list.Words(), expected) {
+		t.Errorf("Error replacing the word list. Expected : %v, Words : %v", expected, list.Words())
+	}
+}
+
+func TestLoadWordsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	content := "# banned words\nkerfuffle\n\n  Sharbert\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Could not write words file, error :%v", err)
+	}
+
+	words, err := LoadWordsFile(path)
+	if err != nil {
+		t.Fatalf("LoadWordsFile errored, error :%v", err)
+	}
+
+	if len(words) != 2 || words[0] != "kerfuffle" || words[1] != "sharbert" {
+		t.Errorf("Unexpected words loaded : %v", words)
+	}
+}
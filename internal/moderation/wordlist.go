@@ -0,0 +1,37 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadWordsFile reads one banned word per line, ignoring blank lines and # comments.
+func LoadWordsFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening banned words file, err :%v", err)
+	}
+	defer file.Close()
+
+	words := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		word, err := NormalizeWord(line)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid banned word %q, err :%v", line, err)
+		}
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error reading banned words file, err :%v", err)
+	}
+
+	return words, nil
+}
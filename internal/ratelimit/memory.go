@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const memoryPruneInterval = time.Minute
+
+// MemoryStore keeps buckets in process, limits are then per instance.
+type MemoryStore struct {
+	mu       sync.Mutex
+	tats     map[string]time.Time
+	prunedAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tats: map[string]time.Time{}}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now)
+
+	tat, allowed := take(s.tats[key], now, rule)
+	if !allowed {
+		return result(s.tats[key], now, rule, false), nil
+	}
+	s.tats[key] = tat
+	return result(tat, now, rule, true), nil
+}
+
+// prune drops full buckets, they are the same as a missing one.
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.prunedAt) < memoryPruneInterval {
+		return
+	}
+	s.prunedAt = now
+
+	for key, tat := range s.tats {
+		if tat.Before(now) {
+			delete(s.tats, key)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeyFunc names who a request is counted against, e.g. a user ID or a client IP.
+type KeyFunc func(r *http.Request) string
+
+// RuleFunc picks the bucket name and rule for a request, ok is false to skip limiting.
+type RuleFunc func(r *http.Request) (name string, rule Rule, ok bool)
+
+type Limiter struct {
+	store   Store
+	keyFunc KeyFunc
+	now     func() time.Time
+}
+
+func New(store Store, keyFunc KeyFunc) *Limiter {
+	return &Limiter{store: store, keyFunc: keyFunc, now: time.Now}
+}
+
+// Middleware limits the requests to next with the rule ruleFor picks for each
+// one, and reports the bucket state in X-RateLimit-* headers.
+func (l *Limiter) Middleware(ruleFor RuleFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, rule, ok := ruleFor(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := name + "|" + l.keyFunc(r)
+		res, err := l.store.Take(r.Context(), key, rule, l.now())
+		if err != nil {
+			// Failing open, an unavailable store shouldn't take the API down with it
+			log.Printf("Could not check rate limit for %v, err :%v", key, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":"Too many requests, slow down"}`))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/flogit2161/Chirpy/internal/database"
+)
+
+// PostgresStore shares buckets between every instance using the database.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	now = now.UTC()
+	tat, err := s.db.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:             key,
+		Now:             now,
+		IntervalSeconds: rule.interval().Seconds(),
+		PeriodSeconds:   rule.Period.Seconds(),
+	})
+	if err == nil {
+		return result(tat, now, rule, true), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+
+	// The update was refused, the bucket is empty
+	tat, err = s.db.GetRateLimitBucket(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+	return result(tat, now, rule, false), nil
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule allows Limit requests per Period, all of which can be spent in a burst.
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRule reads rules written like "30/1m" or "5/1h".
+func ParseRule(raw string) (Rule, error) {
+	rawLimit, rawPeriod, ok := strings.Cut(strings.TrimSpace(raw), "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("Rate limit %q must look like 30/1m", raw)
+	}
+
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil || limit <= 0 {
+		return Rule{}, fmt.Errorf("Rate limit %q must allow a positive number of requests", raw)
+	}
+
+	period, err := time.ParseDuration(rawPeriod)
+	if err != nil || period <= 0 {
+		return Rule{}, fmt.Errorf("Rate limit %q must have a positive period", raw)
+	}
+	return Rule{Limit: limit, Period: period}, nil
+}
+
+// interval is the time one token takes to come back.
+func (r Rule) interval() time.Duration {
+	return r.Period / time.Duration(r.Limit)
+}
+
+// Result is the state of a bucket after a request, as sent in the X-RateLimit headers.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take must be atomic so instances sharing a store
+// never hand out the same token twice.
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error)
+}
+
+// Buckets are stored as the GCRA "theoretical arrival time": the moment the
+// bucket would be full again. It behaves exactly like a token bucket but is a
+// single timestamp, which makes atomic updates in a shared store simple.
+//
+// take returns the new arrival time and whether the request fits in the bucket.
+func take(tat, now time.Time, rule Rule) (time.Time, bool) {
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(rule.interval())
+	return newTat, newTat.Sub(now) <= rule.Period
+}
+
+func result(tat, now time.Time, rule Rule, allowed bool) Result {
+	if tat.Before(now) {
+		tat = now
+	}
+	res := Result{
+		Allowed: allowed,
+		Limit:   rule.Limit,
+		Reset:   tat.Sub(now),
+	}
+
+	res.Remaining = int((rule.Period - res.Reset) / rule.interval())
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+
+	if !allowed {
+		res.RetryAfter = tat.Add(rule.interval()).Sub(now) - rule.Period
+	}
+	return res
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("30/1m")
+	if err != nil || rule.Limit != 30 || rule.Period != time.Minute {
+		t.Errorf("Wrong rule. Rule : %+v, error :%v", rule, err)
+	}
+
+	for _, raw := range []string{"30", "0/1m", "30/0s", "x/1m"} {
+		if _, err := ParseRule(raw); err == nil {
+			t.Errorf("ParseRule accepted %q", raw)
+		}
+	}
+}
+
+func TestMemoryStoreBucket(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	rule := Rule{Limit: 3, Period: 3 * time.Second}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 2; i >= 0; i-- {
+		res, err := store.Take(ctx, "bob", rule, now)
+		if err != nil {
+			t.Fatalf("Take errored, error :%v", err)
+		}
+		if !res.Allowed || res.Remaining != i {
+			t.Errorf("Burst request refused or wrong remaining. Expected : %d, Result : %+v", i, res)
+		}
+	}
+
+	res, _ := store.Take(ctx, "bob", rule, now)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Errorf("Request over the limit was allowed or wrong retry. Result : %+v", res)
+	}
+
+	res, _ = store.Take(ctx, "alice", rule, now)
+	if !res.Allowed {
+		t.Errorf("Another key shared bob's bucket")
+	}
+
+	// One token is back every second
+	res, _ = store.Take(ctx, "bob", rule, now.Add(time.Second))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Refilled token was refused. Result : %+v", res)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter := New(NewMemoryStore(), func(r *http.Request) string { return r.RemoteAddr })
+	ruleFor := func(r *http.Request) (string, Rule, bool) {
+		return "test", Rule{Limit: 1, Period: time.Minute}, r.URL.Path != "/healthz"
+	}
+	handler := limiter.Middleware(ruleFor, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/api/chirps", nil))
+	if rec.Code != 200 || rec.Header().Get("X-RateLimit-Limit") != "1" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Wrong first response. Code : %v, Headers : %v", rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/api/chirps", nil))
+	if rec.Code != 429 || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("Wrong limited response. Code : %v, Headers : %v", rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != 200 || rec.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("Skipped route was limited. Code : %v, Headers : %v", rec.Code, rec.Header())
+	}
+}
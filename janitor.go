@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/flogit2161/Chirpy/internal/loginguard"
+)
+
+const (
+	defaultJanitorInterval = 1 * time.Hour
+	// Revoked tokens are kept a while after their family ends, rotated ones stay
+	// while the family lives so their reuse can still be detected
+	defaultTokenRetention = 7 * 24 * time.Hour
+)
+
+// runTokenJanitor deletes refresh tokens that expired more than retention ago or
+// were revoked that long ago in a family with no live token left, login failures nobody remembers anymore and full rate limit
+// buckets, every interval, until ctx is cancelled.
+func (cfg *apiConfig) runTokenJanitor(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cfg.purgeStaleRefreshTokens(ctx, retention)
+		cfg.purgeStaleLoginFailures(ctx)
+		cfg.purgeFullRateLimitBuckets(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) purgeStaleRefreshTokens(ctx context.Context, retention time.Duration) {
+	purged, err := cfg.db.DeleteStaleRefreshTokens(ctx, retention.Seconds())
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Could not purge stale refresh tokens, err :%v", err)
+		}
+		return
+	}
+
+	cfg.purgedRefreshTokens.Add(purged)
+	if purged > 0 {
+		log.Printf("Purged %d stale refresh tokens", purged)
+	}
+}
+
+// purgeStaleLoginFailures drops the keys whose failures no policy still counts
+// or locks out, the Postgres counterpart of the memory store's prune.
+func (cfg *apiConfig) purgeStaleLoginFailures(ctx context.Context) {
+	retention := max(
+		loginguard.AccountPolicy.Window, loginguard.AccountPolicy.LockoutDuration,
+		loginguard.IPPolicy.Window, loginguard.IPPolicy.LockoutDuration,
+	)
+
+	// Failures are stamped with the application's UTC clock, not the database's
+	purged, err := cfg.db.DeleteStaleLoginFailures(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Could not purge stale login failures, err :%v", err)
+		}
+		return
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d stale login failure counters", purged)
+	}
+}
+
+// purgeFullRateLimitBuckets drops the buckets whose theoretical arrival time has
+// passed: they are full again, so a missing row means exactly the same thing.
+func (cfg *apiConfig) purgeFullRateLimitBuckets(ctx context.Context) {
+	purged, err := cfg.db.DeleteFullRateLimitBuckets(ctx, time.Now().UTC())
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Could not purge full rate limit buckets, err :%v", err)
+		}
+		return
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d full rate limit buckets", purged)
+	}
+}
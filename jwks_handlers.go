@@ -0,0 +1,9 @@
+package main
+
+import "net/http"
+
+// handlerJWKS publishes the public verification keys so other services can check Chirpy tokens.
+func (cfg *apiConfig) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, 200, cfg.jwt.JWKS())
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// optionalViewer returns the authenticated user when a valid bearer token is sent.
+// Reads are public, so a missing, expired or invalid token just makes the request
+// anonymous instead of failing it.
+func (cfg *apiConfig) optionalViewer(r *http.Request) uuid.NullUUID {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userUUID, Valid: true}
+}
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	chirpID := r.PathValue("chirpID")
+	parsedID, err := uuid.Parse(chirpID)
+	if err != nil {
+		respondWithError(w, 400, "Error parsing Chirp ID into a UUID")
+		return
+	}
+
+	_, err = cfg.db.RetrieveChirp(r.Context(), parsedID)
+	if err != nil {
+		respondWithError(w, 404, "Error trying to load the chirp at this ID")
+		return
+	}
+
+	// Liking twice is a no-op so clients can safely retry
+	err = cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+		ChirpID: parsedID,
+		UserID:  userUUID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error liking chirp")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	chirpID := r.PathValue("chirpID")
+	parsedID, err := uuid.Parse(chirpID)
+	if err != nil {
+		respondWithError(w, 400, "Error parsing Chirp ID into a UUID")
+		return
+	}
+
+	err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		ChirpID: parsedID,
+		UserID:  userUUID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error unliking chirp")
+		return
+	}
+
+	w.WriteHeader(204)
+}
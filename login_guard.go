@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// loginKeys are the login guard keys of an attempt: the targeted account and the client address.
+func loginKeys(r *http.Request, email string) (accountKey, ipKey string) {
+	return "account:" + strings.ToLower(strings.TrimSpace(email)), "ip:" + clientIP(r)
+}
+
+// loginAttempt is a login attempt reserved on both guard keys before the credentials are checked.
+type loginAttempt struct {
+	accountKey      string
+	ipKey           string
+	retryAfter      time.Duration
+	accountLocksOut bool
+	ipLocksOut      bool
+}
+
+// beginLoginAttempt counts the attempt up front, so parallel requests can't all
+// get a guess in before the first failure is recorded. retryAfter is set when
+// the attempt is refused, and a refused attempt is counted on neither key.
+func (cfg *apiConfig) beginLoginAttempt(ctx context.Context, accountKey, ipKey string) (loginAttempt, error) {
+	account, err := cfg.accountGuard.Reserve(ctx, accountKey)
+	if err != nil {
+		return loginAttempt{}, err
+	}
+	if account.RetryAfter > 0 {
+		return loginAttempt{retryAfter: account.RetryAfter}, nil
+	}
+
+	ip, err := cfg.ipGuard.Reserve(ctx, ipKey)
+	if err != nil {
+		return loginAttempt{}, err
+	}
+	if ip.RetryAfter > 0 {
+		err = cfg.accountGuard.Release(ctx, accountKey)
+		if err != nil {
+			return loginAttempt{}, err
+		}
+		return loginAttempt{retryAfter: ip.RetryAfter}, nil
+	}
+
+	return loginAttempt{
+		accountKey:      accountKey,
+		ipKey:           ipKey,
+		accountLocksOut: account.LocksOut,
+		ipLocksOut:      ip.LocksOut,
+	}, nil
+}
+
+func respondWithTooManyAttempts(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	respondWithError(w, 429, "Too many failed login attempts, try again later")
+}
+
+// loginAttemptFailed audits the lockouts caused by a failed attempt, which was already counted.
+func (cfg *apiConfig) loginAttemptFailed(r *http.Request, attempt loginAttempt, userID uuid.NullUUID) {
+	if attempt.accountLocksOut {
+		cfg.audit(r, "login_lockout", userID, fmt.Sprintf("%s locked out after repeated failed logins", attempt.accountKey))
+	}
+	if attempt.ipLocksOut {
+		cfg.audit(r, "login_lockout", uuid.NullUUID{}, fmt.Sprintf("%s locked out after repeated failed logins", attempt.ipKey))
+	}
+}
+
+// loginAttemptSucceeded gives back the attempt reserved on both keys once the credentials checked out.
+func (cfg *apiConfig) loginAttemptSucceeded(ctx context.Context, attempt loginAttempt) {
+	err := cfg.accountGuard.Release(ctx, attempt.accountKey)
+	if err != nil {
+		log.Printf("Could not release login attempt for %v, err :%v", attempt.accountKey, err)
+	}
+
+	err = cfg.ipGuard.Release(ctx, attempt.ipKey)
+	if err != nil {
+		log.Printf("Could not release login attempt for %v, err :%v", attempt.ipKey, err)
+	}
+}
+
+// audit writes a security event, failures are only logged so they never block the request.
+func (cfg *apiConfig) audit(r *http.Request, event string, userID uuid.NullUUID, details string) {
+	err := cfg.db.CreateAuditEntry(r.Context(), database.CreateAuditEntryParams{
+		Event:     event,
+		UserID:    userID,
+		IpAddress: clientIP(r),
+		Details:   details,
+	})
+	if err != nil {
+		log.Printf("Could not write audit entry %v, err :%v", event, err)
+	}
+}
@@ -1,44 +1,245 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/flogit2161/Chirpy/internal/auth"
 	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/flogit2161/Chirpy/internal/loginguard"
+	"github.com/flogit2161/Chirpy/internal/mailer"
+	"github.com/flogit2161/Chirpy/internal/moderation"
+	"github.com/flogit2161/Chirpy/internal/ratelimit"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Password     string    `json:"password,omitempty"`
-	Token        string    `json:"token,omitempty"`
-	RefreshToken string    `json:"refresh_token,omitempty"`
-	RedChirpy    bool      `json:"is_chirpy_red"`
+	ID               uuid.UUID `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Email            string    `json:"email,omitempty"`
+	EmailVerified    bool      `json:"email_verified"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	Username         string    `json:"username,omitempty"`
+	Password         string    `json:"password,omitempty"`
+	Token            string    `json:"token,omitempty"`
+	RefreshToken     string    `json:"refresh_token,omitempty"`
+	RedChirpy        bool      `json:"is_chirpy_red"`
+	DisplayName      string    `json:"display_name"`
+	Bio              string    `json:"bio"`
+	AvatarURL        string    `json:"avatar_url"`
 }
 
 type Chirps struct {
+	ID            uuid.UUID     `json:"id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Body          string        `json:"body"`
+	UserID        uuid.UUID     `json:"user_id"`
+	ParentID      *uuid.UUID    `json:"parent_id,omitempty"`
+	ReplyCount    int64         `json:"reply_count"`
+	LikeCount     int64         `json:"like_count"`
+	LikedByMe     bool          `json:"liked_by_me"`
+	RepostOf      *ChirpSummary `json:"repost_of,omitempty"`
+	RepostDeleted bool          `json:"repost_deleted,omitempty"`
+	Snippet       string        `json:"snippet,omitempty"`
+}
+
+// ChirpSummary is the compact view of a reposted chirp embedded in the repost.
+type ChirpSummary struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
 	Body      string    `json:"body"`
 	UserID    uuid.UUID `json:"user_id"`
 }
 
+// ChirpRevision is a body a chirp had before one of its edits.
+type ChirpRevision struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Body      string    `json:"body"`
+}
+
+type chirpsPage struct {
+	Chirps     []Chirps `json:"chirps"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// loadJWTKeys signs with the private key in JWT_SIGNING_KEY_FILE when set, falling
+// back to the JWT_SECRET shared secret. JWT_VERIFICATION_KEY_FILES lists, comma
+// separated, the previous keys whose tokens are still accepted during a rotation.
+// JWT_ISSUER, JWT_AUDIENCE, JWT_ALGORITHMS and JWT_LEEWAY tune token validation.
+func loadJWTKeys(secret string) (*auth.KeySet, error) {
+	signingKey := auth.NewHMACKey(secret)
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		key, err := auth.LoadKeyPEM(path)
+		if err != nil {
+			return nil, err
+		}
+		signingKey = key
+	} else if secret == "" {
+		return nil, fmt.Errorf("JWT_SECRET or JWT_SIGNING_KEY_FILE must be set to sign tokens")
+	}
+
+	verificationKeys := []*auth.SigningKey{}
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := auth.LoadKeyPEM(path)
+		if err != nil {
+			return nil, err
+		}
+		verificationKeys = append(verificationKeys, key)
+	}
+
+	keySet, err := auth.NewKeySet(signingKey, verificationKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := auth.DefaultValidationOptions()
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		opts.Issuer = issuer
+	}
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		opts.Audience = audience
+	}
+	for _, alg := range strings.Split(os.Getenv("JWT_ALGORITHMS"), ",") {
+		if alg = strings.TrimSpace(alg); alg != "" {
+			opts.Algorithms = append(opts.Algorithms, alg)
+		}
+	}
+	// Otherwise every token the server issues would be rejected
+	if len(opts.Algorithms) > 0 && !slices.Contains(opts.Algorithms, signingKey.Algorithm()) {
+		return nil, fmt.Errorf("JWT_ALGORITHMS must include %s, the signing key's algorithm", signingKey.Algorithm())
+	}
+	if rawLeeway := os.Getenv("JWT_LEEWAY"); rawLeeway != "" {
+		leeway, err := time.ParseDuration(rawLeeway)
+		if err != nil || leeway < 0 {
+			return nil, fmt.Errorf("JWT_LEEWAY must be a duration such as 30s")
+		}
+		opts.Leeway = leeway
+	}
+
+	return keySet.WithOptions(opts), nil
+}
+
+// loadHashParams reads the argon2id costs from ARGON2_MEMORY_KIB, ARGON2_ITERATIONS
+// and ARGON2_PARALLELISM. Raising them makes existing hashes be upgraded on login.
+func loadHashParams() (auth.HashParams, error) {
+	params := auth.DefaultHashParams()
+
+	if raw := os.Getenv("ARGON2_MEMORY_KIB"); raw != "" {
+		memory, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return params, fmt.Errorf("ARGON2_MEMORY_KIB must be a number of KiB")
+		}
+		params.Memory = uint32(memory)
+	}
+	if raw := os.Getenv("ARGON2_ITERATIONS"); raw != "" {
+		iterations, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return params, fmt.Errorf("ARGON2_ITERATIONS must be a number")
+		}
+		params.Iterations = uint32(iterations)
+	}
+	if raw := os.Getenv("ARGON2_PARALLELISM"); raw != "" {
+		parallelism, err := strconv.ParseUint(raw, 10, 8)
+		if err != nil {
+			return params, fmt.Errorf("ARGON2_PARALLELISM must be a number up to 255")
+		}
+		params.Parallelism = uint8(parallelism)
+	}
+	return params, nil
+}
+
+// loadMailer sends real emails when MAILER=smtp, otherwise emails are only
+// logged to MAIL_LOG_FILE (or stderr) for local development.
+func loadMailer() (mailer.Mailer, error) {
+	if os.Getenv("MAILER") == "smtp" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &mailer.SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}, nil
+	}
+
+	logFile := os.Getenv("MAIL_LOG_FILE")
+	if logFile == "" {
+		return mailer.NewLogMailer(os.Stderr), nil
+	}
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open mail log file, err :%v", err)
+	}
+	return mailer.NewLogMailer(f), nil
+}
+
 func main() {
 	godotenv.Load()
 	dbURL := os.Getenv("DB_URL")
 	platformPermission := os.Getenv("PLATFORM")
 	jwtToken := os.Getenv("JWT_SECRET")
 	polkaKey := os.Getenv("POLKA_KEY")
+	adminKey := os.Getenv("ADMIN_API_KEY")
+	bannedWordsFile := os.Getenv("BANNED_WORDS_FILE")
+	baseURL := os.Getenv("BASE_URL")
+	janitorInterval := durationFromEnv("TOKEN_JANITOR_INTERVAL", defaultJanitorInterval)
+	tokenRetention := durationFromEnv("TOKEN_RETENTION", defaultTokenRetention)
+	bannedWordsRefresh := durationFromEnv("BANNED_WORDS_REFRESH_INTERVAL", defaultBannedWordsRefresh)
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	jwtKeys, err := loadJWTKeys(jwtToken)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	hashParams, err := loadHashParams()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = auth.SetHashParams(hashParams)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 2FA stays unavailable until a key is set, secrets are never stored unencrypted
+	var totpKey []byte
+	if rawTOTPKey := os.Getenv("TOTP_ENCRYPTION_KEY"); rawTOTPKey != "" {
+		totpKey, err = auth.ParseEncryptionKey(rawTOTPKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	emailSender, err := loadMailer()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -46,12 +247,70 @@ func main() {
 	}
 
 	dbQueries := database.New(db)
+
+	bannedWords, err := dbQueries.ListBannedWords(context.Background())
+	if err != nil {
+		log.Fatal("Could not load banned words from database")
+	}
+	fileWords := []string{}
+	if bannedWordsFile != "" {
+		fileWords, err = moderation.LoadWordsFile(bannedWordsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	wordList := moderation.NewWordList(append(bannedWords, fileWords...)...)
+
+	// Without the key, users who already turned 2FA on could never log in again
+	if totpKey == nil {
+		totpUsers, err := dbQueries.CountTOTPEnabledUsers(context.Background())
+		if err != nil {
+			log.Fatal("Could not check for users with two-factor authentication")
+		}
+		if totpUsers > 0 {
+			log.Fatalf("TOTP_ENCRYPTION_KEY is required, %d users have two-factor authentication enabled", totpUsers)
+		}
+	}
+
+	// Failed logins are counted in Postgres so every instance sees them, LOGIN_GUARD_STORE=memory keeps them per process
+	var loginStore loginguard.Store = loginguard.NewPostgresStore(dbQueries)
+	if os.Getenv("LOGIN_GUARD_STORE") == "memory" {
+		loginStore = loginguard.NewMemoryStore()
+	}
+
+	rateLimitStore, rateLimitStoreName, err := loadRateLimitStore(dbQueries)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Rate limit buckets are kept in %s", rateLimitStoreName)
+
+	rateLimits, err := loadRateLimits()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	passwordPolicy, err := loadPasswordPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	apiCfg := &apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             dbQueries,
-		platform:       platformPermission,
-		jwt:            jwtToken,
-		polka:          polkaKey,
+		fileserverHits:  atomic.Int32{},
+		db:              dbQueries,
+		sqlDB:           db,
+		platform:        platformPermission,
+		jwt:             jwtKeys,
+		polka:           polkaKey,
+		adminKey:        adminKey,
+		bannedWords:     wordList,
+		fileBannedWords: moderation.NewWordList(fileWords...),
+		filter:          moderation.NewWordFilter(wordList),
+		mailer:          emailSender,
+		baseURL:         strings.TrimSuffix(baseURL, "/"),
+		totpKey:         totpKey,
+		accountGuard:    loginguard.New(loginStore, loginguard.AccountPolicy),
+		ipGuard:         loginguard.New(loginStore, loginguard.IPPolicy),
+		passwordPolicy:  passwordPolicy,
 	}
 
 	serveMux := http.NewServeMux()
@@ -60,28 +319,113 @@ func main() {
 
 	serveMux.Handle("/app/", apiCfg.middlewareMetricsInc(handler))
 	serveMux.HandleFunc("GET /api/healthz", handlerHealth)
+	serveMux.HandleFunc("GET /.well-known/jwks.json", apiCfg.handlerJWKS)
 	serveMux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
+	serveMux.HandleFunc("GET /admin/banned-words", apiCfg.handlerListBannedWords)
 	serveMux.HandleFunc("GET /api/chirps", apiCfg.handlerRetrieveAllChirps)
+	serveMux.HandleFunc("GET /api/chirps/search", apiCfg.handlerSearchChirps)
 	serveMux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerRetrieveChirp)
+	serveMux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.handlerRetrieveChirpRevisions)
+	serveMux.HandleFunc("GET /api/chirps/{chirpID}/replies", apiCfg.handlerRetrieveReplies)
+	serveMux.HandleFunc("GET /api/users/me", apiCfg.handlerRetrieveMe)
+	serveMux.HandleFunc("GET /api/users/me/mentions", apiCfg.handlerRetrieveMyMentions)
+	serveMux.HandleFunc("GET /api/users/verify", apiCfg.handlerVerifyEmail)
+	serveMux.HandleFunc("GET /api/users/{userID}", apiCfg.handlerRetrieveUser)
+	serveMux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.handlerListFollowers)
+	serveMux.HandleFunc("GET /api/users/{userID}/following", apiCfg.handlerListFollowing)
+	serveMux.HandleFunc("GET /api/timeline", apiCfg.handlerRetrieveTimeline)
+	serveMux.HandleFunc("GET /api/tags/trending", apiCfg.handlerRetrieveTrendingTags)
+	serveMux.HandleFunc("GET /api/tags/{tag}/chirps", apiCfg.handlerRetrieveTagChirps)
+	serveMux.HandleFunc("GET /api/sessions", apiCfg.handlerListSessions)
 
 	serveMux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
+	serveMux.HandleFunc("POST /admin/banned-words", apiCfg.handlerAddBannedWord)
 	serveMux.HandleFunc("POST /api/chirps", apiCfg.handlerCreateChirp)
 	serveMux.HandleFunc("POST /api/users", apiCfg.handlerCreateUser)
 	serveMux.HandleFunc("POST /api/login", apiCfg.handlerLogIn)
+	serveMux.HandleFunc("POST /api/login/2fa", apiCfg.handlerLogInTwoFactor)
+	serveMux.HandleFunc("POST /api/users/me/2fa/setup", apiCfg.handlerSetupTwoFactor)
+	serveMux.HandleFunc("POST /api/users/me/2fa/confirm", apiCfg.handlerConfirmTwoFactor)
 	serveMux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
 	serveMux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
+	serveMux.HandleFunc("POST /api/password/forgot", apiCfg.handlerForgotPassword)
+	serveMux.HandleFunc("POST /api/password/reset", apiCfg.handlerResetPassword)
+	serveMux.HandleFunc("POST /api/sessions/revoke-all", apiCfg.handlerRevokeAllSessions)
 	serveMux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerUpgradeRedChirpy)
+	serveMux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.handlerFollowUser)
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.handlerLikeChirp)
 
 	serveMux.HandleFunc("PUT /api/users", apiCfg.handlerUpdateUserLogs)
+	serveMux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.handlerUpdateChirp)
 
 	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
+	serveMux.HandleFunc("DELETE /admin/banned-words/{word}", apiCfg.handlerRemoveBannedWord)
+	serveMux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.handlerUnfollowUser)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.handlerUnlikeChirp)
+	serveMux.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.handlerRevokeSession)
+
+	err = checkRateLimitPatterns(serveMux, rateLimits)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	limiter := ratelimit.New(rateLimitStore, apiCfg.rateLimitKey)
 
 	server := &http.Server{
 		Addr:    ":8080",
-		Handler: serveMux,
+		Handler: limiter.Middleware(rateLimitRules(serveMux, rateLimits), serveMux),
 	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	janitorDone := make(chan struct{})
+	go func() {
+		defer close(janitorDone)
+		apiCfg.runTokenJanitor(ctx, janitorInterval, tokenRetention)
+	}()
+
+	bannedWordsDone := make(chan struct{})
+	go func() {
+		defer close(bannedWordsDone)
+		apiCfg.runBannedWordsRefresh(ctx, bannedWordsRefresh)
+	}()
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Printf("Error shutting down server, err :%v", err)
+		}
+	}()
+
 	err = server.ListenAndServe()
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatal("Error L/S server")
 	}
+
+	// ListenAndServe returns as soon as Shutdown starts, wait for in-flight requests to drain
+	stop()
+	<-shutdownDone
+	apiCfg.background.Wait()
+	<-janitorDone
+	<-bannedWordsDone
+}
+
+// durationFromEnv reads a duration such as "30m" or "168h", or falls back to def when unset.
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s must be a positive duration, e.g. 1h", key)
+	}
+	return d
 }
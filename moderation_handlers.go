@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/moderation"
+)
+
+// Every instance keeps its own copy of the list, refreshed from the database this often
+const defaultBannedWordsRefresh = 1 * time.Minute
+
+type bannedWordsResponse struct {
+	Words []string `json:"words"`
+}
+
+// checkAdminKey answers the request itself when the ApiKey header is missing or wrong.
+func (cfg *apiConfig) checkAdminKey(w http.ResponseWriter, r *http.Request) bool {
+	key, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Error getting the API Key from header")
+		return false
+	}
+
+	if cfg.adminKey == "" || key != cfg.adminKey {
+		respondWithError(w, 401, "API Key does not match")
+		return false
+	}
+	return true
+}
+
+func (cfg *apiConfig) handlerListBannedWords(w http.ResponseWriter, r *http.Request) {
+	if !cfg.checkAdminKey(w, r) {
+		return
+	}
+
+	respondWithJSON(w, 200, bannedWordsResponse{Words: cfg.bannedWords.Words()})
+}
+
+func (cfg *apiConfig) handlerAddBannedWord(w http.ResponseWriter, r *http.Request) {
+	if !cfg.checkAdminKey(w, r) {
+		return
+	}
+
+	type bodyRequest struct {
+		Word string `json:"word"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+
+	defer r.Body.Close()
+	request := bodyRequest{}
+
+	err := decoder.Decode(&request)
+	if err != nil {
+		respondWithError(w, 500, "Error decoding the request")
+		return
+	}
+
+	word, err := moderation.NormalizeWord(request.Word)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	err = cfg.db.AddBannedWord(r.Context(), word)
+	if err != nil {
+		respondWithError(w, 500, "Error saving the banned word")
+		return
+	}
+	cfg.bannedWords.Add(word)
+
+	respondWithJSON(w, 201, bannedWordsResponse{Words: cfg.bannedWords.Words()})
+}
+
+func (cfg *apiConfig) handlerRemoveBannedWord(w http.ResponseWriter, r *http.Request) {
+	if !cfg.checkAdminKey(w, r) {
+		return
+	}
+
+	word, err := moderation.NormalizeWord(r.PathValue("word"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	// Words from BANNED_WORDS_FILE would come back on the next restart
+	if cfg.fileBannedWords.Contains(word) {
+		respondWithError(w, 409, "Word comes from the banned words file, remove it there")
+		return
+	}
+
+	removed, err := cfg.db.RemoveBannedWord(r.Context(), word)
+	if err != nil {
+		respondWithError(w, 500, "Error removing the banned word")
+		return
+	}
+	cfg.bannedWords.Remove(word)
+
+	if removed == 0 {
+		respondWithError(w, 404, "Word is not banned")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// runBannedWordsRefresh reloads the banned words from the database every interval
+// until ctx is cancelled, so changes made through another instance get picked up.
+func (cfg *apiConfig) runBannedWordsRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cfg.refreshBannedWords(ctx)
+	}
+}
+
+func (cfg *apiConfig) refreshBannedWords(ctx context.Context) {
+	words, err := cfg.db.ListBannedWords(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Could not refresh banned words, err :%v", err)
+		}
+		return
+	}
+
+	cfg.bannedWords.Replace(append(words, cfg.fileBannedWords.Words()...)...)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageCursor is the position of the last item of a page, keyed on
+// (created_at, id) so that rows sharing a timestamp are never skipped.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor returns a nil cursor when the client did not send one.
+func decodeCursor(encoded string) (*pageCursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("Cursor is not valid base64")
+	}
+
+	createdAtString, idString, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, fmt.Errorf("Cursor is malformed")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtString)
+	if err != nil {
+		return nil, fmt.Errorf("Cursor has an invalid timestamp")
+	}
+
+	id, err := uuid.Parse(idString)
+	if err != nil {
+		return nil, fmt.Errorf("Cursor has an invalid id")
+	}
+
+	return &pageCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// params converts the cursor into the nullable arguments taken by the keyset queries.
+func (c *pageCursor) params() (sql.NullTime, uuid.NullUUID) {
+	if c == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}, uuid.NullUUID{UUID: c.ID, Valid: true}
+}
+
+func parseLimit(raw string) (int, error) {
+	if raw == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, fmt.Errorf("Limit must be a number between 1 and %d", maxPageLimit)
+	}
+	return limit, nil
+}
+
+// parseTimeParam reads an optional RFC3339 query parameter, normalised to UTC
+// to match the timestamps Postgres hands back for our TIMESTAMP columns.
+func parseTimeParam(raw string) (sql.NullTime, error) {
+	if raw == "" {
+		return sql.NullTime{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: parsed.UTC(), Valid: true}, nil
+}
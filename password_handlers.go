@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/flogit2161/Chirpy/internal/mailer"
+)
+
+// passwordResetMailTimeout bounds the work left running after answering a reset request.
+const passwordResetMailTimeout = 30 * time.Second
+
+func (cfg *apiConfig) handlerForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	params := parameters{}
+
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Error decoding the request")
+		return
+	}
+
+	if params.Email == "" {
+		respondWithError(w, 400, "Email is required")
+		return
+	}
+
+	// Always answer the same way so the endpoint can't be used to probe for accounts:
+	// the lookup and the mail happen after answering, so neither the response time
+	// nor an error tells whether the account exists
+	ctx := context.WithoutCancel(r.Context())
+	cfg.background.Go(func() {
+		ctx, cancel := context.WithTimeout(ctx, passwordResetMailTimeout)
+		defer cancel()
+		cfg.sendPasswordReset(ctx, params.Email)
+	})
+
+	w.WriteHeader(202)
+}
+
+// sendPasswordReset mails a reset token to the account at email, if there is one.
+// Errors are only logged, nobody is waiting for the answer.
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, email string) {
+	user, err := cfg.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("Could not look up the account for a password reset, err :%v", err)
+		return
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("Could not generate a password reset token for user %v, err :%v", user.ID, err)
+		return
+	}
+
+	// Only the hash is stored, and asking again replaces any reset token still pending
+	err = cfg.db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashRefreshToken(token),
+		UserID:    user.ID,
+	})
+	if err != nil {
+		log.Printf("Could not save the password reset token for user %v, err :%v", user.ID, err)
+		return
+	}
+
+	err = cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: "Someone asked to reset the password of your Chirpy account.\n\n" +
+			"Send this token with your new password to POST " + cfg.baseURL + "/api/password/reset within 1 hour:\n\n" +
+			token + "\n\nIf it wasn't you, you can ignore this email.\n",
+	})
+	if err != nil {
+		log.Printf("Could not send password reset email to user %v, err :%v", user.ID, err)
+	}
+}
+
+func (cfg *apiConfig) handlerResetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	params := parameters{}
+
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Error decoding the request")
+		return
+	}
+
+	if params.Token == "" || params.Password == "" {
+		respondWithError(w, 400, "Token and password are required")
+		return
+	}
+
+	tokenHash := auth.HashRefreshToken(params.Token)
+
+	// The token is only looked at here, it is consumed with the password change below
+	tokenUser, err := cfg.db.GetPasswordResetTokenUser(r.Context(), tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, "Reset token is either expired, already used or does not exist")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Error checking the reset token")
+		return
+	}
+
+	if !cfg.checkPassword(w, params.Password, tokenUser.Email) {
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, 500, "Error hashing the password")
+		return
+	}
+
+	// The new password only sticks if every old session is logged out with it
+	tx, err := cfg.sqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Error resetting the password")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.ResetPasswordWithToken(r.Context(), database.ResetPasswordWithTokenParams{
+		TokenHash:      tokenHash,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Reset token is either expired, already used or does not exist")
+		return
+	}
+
+	// Log out every session that may have been opened with the old password
+	err = qtx.RevokeAllUserRefreshTokens(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Error revoking the user's refresh tokens")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Error resetting the password")
+		return
+	}
+
+	w.WriteHeader(204)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+)
+
+type passwordPolicyErrorResponse struct {
+	Error       string                     `json:"error"`
+	FailedRules []auth.PasswordRuleFailure `json:"failed_rules"`
+}
+
+// loadPasswordPolicy reads PASSWORD_MIN_LENGTH and PASSWORD_MIN_ENTROPY, and loads
+// the sorted breached password hashes in BREACHED_PASSWORDS_FILE when it is set.
+func loadPasswordPolicy() (auth.PasswordPolicy, error) {
+	policy := auth.DefaultPasswordPolicy()
+
+	if raw := os.Getenv("PASSWORD_MIN_LENGTH"); raw != "" {
+		minLength, err := strconv.Atoi(raw)
+		if err != nil || minLength < 1 {
+			return policy, fmt.Errorf("PASSWORD_MIN_LENGTH must be a positive number")
+		}
+		policy.MinLength = minLength
+	}
+
+	if raw := os.Getenv("PASSWORD_MIN_ENTROPY"); raw != "" {
+		minEntropy, err := strconv.ParseFloat(raw, 64)
+		if err != nil || minEntropy < 0 {
+			return policy, fmt.Errorf("PASSWORD_MIN_ENTROPY must be a number of bits")
+		}
+		policy.MinEntropy = minEntropy
+	}
+
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		breached, err := auth.OpenBreachedList(path)
+		if err != nil {
+			return policy, err
+		}
+		log.Printf("Checking passwords against the breached hashes in %s", path)
+		policy.Breached = breached
+	}
+	return policy, nil
+}
+
+// checkPassword answers 400 with every rule the new password broke and returns
+// false, so the handler can stop. email may be empty when it isn't known.
+func (cfg *apiConfig) checkPassword(w http.ResponseWriter, password, email string) bool {
+	err := cfg.passwordPolicy.Check(password, email)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *auth.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		respondWithError(w, 500, "Error checking the password")
+		return false
+	}
+
+	respondWithJSON(w, 400, passwordPolicyErrorResponse{
+		Error:       "Password does not meet the password policy",
+		FailedRules: policyErr.Failures,
+	})
+	return false
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/flogit2161/Chirpy/internal/ratelimit"
+)
+
+// defaultRateLimit applies to every route without a stricter rule below.
+const defaultRateLimit = "300/1m"
+
+// routeRateLimits are stricter limits on the routes that create things or cost us
+// an email or a password hash. RATE_LIMITS overrides them, e.g.
+// "POST /api/chirps=10/1m;POST /api/users=5/1h".
+var routeRateLimits = map[string]string{
+	"POST /api/chirps":          "30/1m",
+	"POST /api/users":           "5/1h",
+	"POST /api/login":           "20/1m",
+	"POST /api/password/forgot": "5/1h",
+	"POST /api/password/reset":  "10/1h",
+}
+
+// loadRateLimitStore picks where buckets live from RATE_LIMIT_STORE. "memory", the
+// default, suits a single instance; "postgres" shares the limits between instances
+// at the cost of a database write per request.
+func loadRateLimitStore(db *database.Queries) (ratelimit.Store, string, error) {
+	switch store := os.Getenv("RATE_LIMIT_STORE"); store {
+	case "", "memory":
+		return ratelimit.NewMemoryStore(), "memory", nil
+	case "postgres":
+		return ratelimit.NewPostgresStore(db), store, nil
+	default:
+		return nil, "", fmt.Errorf("RATE_LIMIT_STORE must be memory or postgres, not %q", store)
+	}
+}
+
+// loadRateLimits parses the rules by route pattern, "default" holding the fallback rule.
+func loadRateLimits() (map[string]ratelimit.Rule, error) {
+	raw := map[string]string{"default": defaultRateLimit}
+	for pattern, rule := range routeRateLimits {
+		raw[pattern] = rule
+	}
+	if rawDefault := os.Getenv("RATE_LIMIT_DEFAULT"); rawDefault != "" {
+		raw["default"] = rawDefault
+	}
+	for _, entry := range strings.Split(os.Getenv("RATE_LIMITS"), ";") {
+		pattern, rule, ok := strings.Cut(entry, "=")
+		if ok {
+			raw[strings.TrimSpace(pattern)] = rule
+		}
+	}
+
+	rules := map[string]ratelimit.Rule{}
+	for pattern, rawRule := range raw {
+		rule, err := ratelimit.ParseRule(rawRule)
+		if err != nil {
+			return nil, err
+		}
+		rules[pattern] = rule
+	}
+	return rules, nil
+}
+
+// checkRateLimitPatterns refuses rules for patterns the mux doesn't serve, a typo
+// such as "POST /api/chirp" would otherwise silently leave the route on the default.
+func checkRateLimitPatterns(mux *http.ServeMux, rules map[string]ratelimit.Rule) error {
+	for pattern := range rules {
+		if pattern == "default" {
+			continue
+		}
+
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			method, path = http.MethodGet, pattern
+		}
+		// Wildcards match any segment, their own name will do
+		path = strings.NewReplacer("{", "", "}", "", "...", "").Replace(path)
+
+		r, err := http.NewRequest(method, path, nil)
+		if err == nil {
+			_, served := mux.Handler(r)
+			if served == pattern {
+				continue
+			}
+		}
+		return fmt.Errorf("RATE_LIMITS has a rule for %q, which is not a route", pattern)
+	}
+	return nil
+}
+
+// rateLimitKey counts authenticated requests per user and anonymous ones per client IP.
+func (cfg *apiConfig) rateLimitKey(r *http.Request) string {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err == nil {
+		userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+		if err == nil {
+			return "user:" + userUUID.String()
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// rateLimitRules picks the rule of the route the mux will serve, the health
+// check and static files are never limited.
+func rateLimitRules(mux *http.ServeMux, rules map[string]ratelimit.Rule) ratelimit.RuleFunc {
+	return func(r *http.Request) (string, ratelimit.Rule, bool) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" || pattern == "GET /api/healthz" || pattern == "/app/" {
+			return "", ratelimit.Rule{}, false
+		}
+
+		if rule, ok := rules[pattern]; ok {
+			return pattern, rule, true
+		}
+		return "default", rules["default"], true
+	}
+}
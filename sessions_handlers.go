@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxUserAgentLength = 512
+
+// Session is one login, it lives as long as its refresh token family.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// clientIP uses the connection address only, forwarded headers can be set by anyone.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientUserAgent makes the header safe to store: invalid UTF-8 is replaced and the
+// value is cut to at most maxUserAgentLength bytes without splitting a character.
+func clientUserAgent(r *http.Request) string {
+	userAgent := strings.ToValidUTF8(r.UserAgent(), "\uFFFD")
+	if len(userAgent) <= maxUserAgentLength {
+		return userAgent
+	}
+
+	end := maxUserAgentLength
+	for end > 0 && !utf8.RuneStart(userAgent[end]) {
+		end--
+	}
+	return userAgent[:end]
+}
+
+func (cfg *apiConfig) handlerListSessions(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	rows, err := cfg.db.ListUserSessions(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, 500, "Error retrieving the user's sessions")
+		return
+	}
+
+	sessions := []Session{}
+	for _, row := range rows {
+		sessions = append(sessions, Session{
+			ID:         row.FamilyID,
+			UserAgent:  row.UserAgent,
+			IPAddress:  row.IpAddress,
+			SignedInAt: row.SignedInAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt:  row.ExpiresAt,
+		})
+	}
+
+	respondWithJSON(w, 200, sessions)
+}
+
+func (cfg *apiConfig) handlerRevokeSession(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, 400, "Session ID is not a valid UUID")
+		return
+	}
+
+	revoked, err := cfg.db.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		FamilyID: sessionID,
+		UserID:   userUUID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error revoking the session")
+		return
+	}
+
+	if revoked == 0 {
+		respondWithError(w, 404, "Session can't be found")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	err = cfg.db.RevokeAllUserRefreshTokens(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, 500, "Error revoking the user's sessions")
+		return
+	}
+
+	w.WriteHeader(204)
+}
@@ -0,0 +1,10 @@
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log(id, created_at, event, user_id, ip_address, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+);
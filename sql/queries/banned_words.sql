@@ -0,0 +1,15 @@
+-- name: ListBannedWords :many
+SELECT word FROM banned_words
+ORDER BY word ASC;
+
+-- name: AddBannedWord :exec
+INSERT INTO banned_words(word, created_at)
+VALUES (
+    $1,
+    NOW()
+)
+ON CONFLICT (word) DO NOTHING;
+
+-- name: RemoveBannedWord :execrows
+DELETE FROM banned_words
+WHERE word = $1;
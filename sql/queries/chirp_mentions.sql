@@ -0,0 +1,24 @@
+-- name: CreateChirpMentions :exec
+INSERT INTO chirp_mentions(chirp_id, user_id, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, users.id, NOW()
+FROM users
+WHERE LOWER(users.username) = ANY(sqlc.arg('usernames')::text[])
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: RetrieveMentions :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+   OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: DeleteStaleChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = sqlc.arg('chirp_id')::uuid
+  AND user_id NOT IN (
+      SELECT users.id FROM users
+      WHERE LOWER(users.username) = ANY(sqlc.arg('usernames')::text[])
+  );
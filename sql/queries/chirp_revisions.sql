@@ -0,0 +1,17 @@
+-- name: UpdateChirp :one
+WITH revision AS (
+    INSERT INTO chirp_revisions(id, created_at, chirp_id, body)
+    SELECT gen_random_uuid(), NOW(), chirps.id, chirps.body
+    FROM chirps
+    WHERE chirps.id = $1
+)
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY created_at DESC;
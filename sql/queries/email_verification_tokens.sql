@@ -0,0 +1,32 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens(token_hash, created_at, user_id, email, expires_at, used_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    NOW() + INTERVAL '24 hours',
+    NULL
+);
+
+-- name: VerifyEmailWithToken :one
+WITH used_token AS (
+    UPDATE email_verification_tokens
+    SET used_at = NOW()
+    WHERE token_hash = $1
+      AND used_at IS NULL
+      AND expires_at > NOW()
+    RETURNING user_id, email
+)
+UPDATE users
+SET email_verified_at = NOW(),
+    updated_at = NOW()
+FROM used_token
+WHERE users.id = used_token.user_id
+  AND users.email = used_token.email
+RETURNING users.*;
+
+-- name: DeletePendingEmailVerificationTokens :exec
+DELETE FROM email_verification_tokens
+WHERE user_id = $1
+  AND used_at IS NULL;
@@ -0,0 +1,40 @@
+-- name: CreateChirpHashtags :exec
+WITH tags AS (
+    INSERT INTO hashtags(id, created_at, name)
+    SELECT gen_random_uuid(), NOW(), tag
+    FROM UNNEST(sqlc.arg('tags')::text[]) AS tag
+    ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+    RETURNING id
+)
+INSERT INTO chirp_hashtags(chirp_id, hashtag_id, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, tags.id, NOW()
+FROM tags
+ON CONFLICT (chirp_id, hashtag_id) DO NOTHING;
+
+-- name: RetrieveChirpsByHashtag :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE hashtags.name = sqlc.arg('tag')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+   OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: RetrieveTrendingHashtags :many
+SELECT hashtags.name, COUNT(*) AS uses
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE chirp_hashtags.created_at >= NOW() - make_interval(secs => sqlc.arg('window_seconds')::float8)
+GROUP BY hashtags.name
+ORDER BY uses DESC, hashtags.name ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: DeleteStaleChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = sqlc.arg('chirp_id')::uuid
+  AND hashtag_id NOT IN (
+      SELECT hashtags.id FROM hashtags
+      WHERE hashtags.name = ANY(sqlc.arg('tags')::text[])
+  );
@@ -0,0 +1,43 @@
+-- name: ReserveLoginAttempt :one
+INSERT INTO login_failures(key, failures, last_failure_at, previous_failure_at)
+VALUES (sqlc.arg('key'), 1, sqlc.arg('now')::timestamp, NULL)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_failures.last_failure_at < sqlc.arg('now')::timestamp - make_interval(secs => sqlc.arg('window_seconds')::float8) THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    previous_failure_at = CASE
+        WHEN login_failures.last_failure_at < sqlc.arg('now')::timestamp - make_interval(secs => sqlc.arg('window_seconds')::float8) THEN NULL
+        ELSE login_failures.last_failure_at
+    END,
+    last_failure_at = sqlc.arg('now')::timestamp
+WHERE login_failures.failures = 0
+   OR login_failures.last_failure_at < sqlc.arg('now')::timestamp - make_interval(secs => sqlc.arg('window_seconds')::float8)
+   OR login_failures.last_failure_at + make_interval(secs => CASE
+        WHEN login_failures.failures >= sqlc.arg('lockout_threshold')::int THEN sqlc.arg('lockout_seconds')::float8
+        WHEN login_failures.failures <= sqlc.arg('free_attempts')::int THEN 0
+        ELSE LEAST(
+            sqlc.arg('base_delay_seconds')::float8 * power(2, login_failures.failures - sqlc.arg('free_attempts')::int - 1),
+            sqlc.arg('max_delay_seconds')::float8
+        )
+    END) <= sqlc.arg('now')::timestamp
+RETURNING *;
+
+-- name: GetLoginFailures :one
+SELECT * FROM login_failures
+WHERE key = $1;
+
+-- name: ReleaseLoginFailure :exec
+UPDATE login_failures
+SET failures = GREATEST(failures - 1, 0),
+    last_failure_at = COALESCE(previous_failure_at, last_failure_at),
+    previous_failure_at = NULL
+WHERE key = $1;
+
+-- name: ResetLoginFailures :exec
+DELETE FROM login_failures
+WHERE key = $1;
+
+-- name: DeleteStaleLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < sqlc.arg('before')::timestamp;
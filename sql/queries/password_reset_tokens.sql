@@ -0,0 +1,42 @@
+-- name: CreatePasswordResetToken :exec
+WITH previous_tokens AS (
+    DELETE FROM password_reset_tokens
+    WHERE user_id = $2
+      AND used_at IS NULL
+)
+INSERT INTO password_reset_tokens(token_hash, created_at, user_id, expires_at, used_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    NOW() + INTERVAL '1 hour',
+    NULL
+);
+
+-- name: GetPasswordResetTokenUser :one
+SELECT users.* FROM users
+JOIN password_reset_tokens ON password_reset_tokens.user_id = users.id
+WHERE password_reset_tokens.token_hash = $1
+  AND password_reset_tokens.used_at IS NULL
+  AND password_reset_tokens.expires_at > NOW();
+
+-- name: ResetPasswordWithToken :one
+WITH used_token AS (
+    UPDATE password_reset_tokens
+    SET used_at = NOW()
+    WHERE token_hash = $1
+      AND used_at IS NULL
+      AND expires_at > NOW()
+    RETURNING user_id
+)
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+FROM used_token
+WHERE users.id = used_token.user_id
+RETURNING users.*;
+
+-- name: DeletePendingPasswordResetTokens :exec
+DELETE FROM password_reset_tokens
+WHERE user_id = $1
+  AND used_at IS NULL;
@@ -0,0 +1,19 @@
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets(key, tat)
+VALUES (
+    sqlc.arg('key'),
+    sqlc.arg('now')::timestamp + make_interval(secs => sqlc.arg('interval_seconds')::float8)
+)
+ON CONFLICT (key) DO UPDATE
+SET tat = GREATEST(rate_limit_buckets.tat, sqlc.arg('now')::timestamp) + make_interval(secs => sqlc.arg('interval_seconds')::float8)
+WHERE GREATEST(rate_limit_buckets.tat, sqlc.arg('now')::timestamp) + make_interval(secs => sqlc.arg('interval_seconds')::float8)
+    <= sqlc.arg('now')::timestamp + make_interval(secs => sqlc.arg('period_seconds')::float8)
+RETURNING tat;
+
+-- name: GetRateLimitBucket :one
+SELECT tat FROM rate_limit_buckets
+WHERE key = $1;
+
+-- name: DeleteFullRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE tat < sqlc.arg('now')::timestamp;
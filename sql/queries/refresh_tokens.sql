@@ -1,29 +1,116 @@
 -- name: GenerateRefreshToken :one
 
-INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens(token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, rotated_at, user_agent, ip_address, last_used_at)
 VALUES(
     $1,
     NOW(),
     NOW(),
     $2,
     NOW() + INTERVAL '60 days',
-    NULL
+    NULL,
+    gen_random_uuid(),
+    NULL,
+    $3,
+    $4,
+    NOW()
 )
 RETURNING *;
 
 
--- name: GetUserFromRefreshToken :one
-SELECT users.*
-FROM users
-JOIN refresh_tokens ON users.id = refresh_tokens.user_id
-WHERE refresh_tokens.token = $1
-  AND refresh_tokens.revoked_at IS NULL
-  AND refresh_tokens.expires_at > NOW();
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1;
+
+
+-- name: RotateRefreshToken :one
+WITH rotated AS (
+    UPDATE refresh_tokens
+    SET rotated_at = NOW(),
+        revoked_at = NOW(),
+        updated_at = NOW()
+    WHERE token_hash = sqlc.arg('old_token_hash')
+      AND revoked_at IS NULL
+      AND expires_at > NOW()
+    RETURNING user_id, family_id
+)
+INSERT INTO refresh_tokens(token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, rotated_at, user_agent, ip_address, last_used_at)
+SELECT
+    sqlc.arg('new_token_hash')::text,
+    NOW(),
+    NOW(),
+    rotated.user_id,
+    NOW() + INTERVAL '60 days',
+    NULL,
+    rotated.family_id,
+    NULL,
+    sqlc.arg('user_agent')::text,
+    sqlc.arg('ip_address')::text,
+    NOW()
+FROM rotated
+RETURNING *;
 
 
 -- name: RevokeToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1
+WHERE token_hash = $1
 RETURNING *;
+
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+  AND revoked_at IS NULL;
+
+
+-- name: RevokeAllUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND revoked_at IS NULL;
+
+
+-- name: ListUserSessions :many
+SELECT
+    refresh_tokens.family_id,
+    refresh_tokens.user_agent,
+    refresh_tokens.ip_address,
+    refresh_tokens.last_used_at,
+    refresh_tokens.expires_at,
+    (
+        SELECT MIN(family.created_at)
+        FROM refresh_tokens AS family
+        WHERE family.family_id = refresh_tokens.family_id
+    )::timestamp AS signed_in_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+  AND refresh_tokens.revoked_at IS NULL
+  AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC;
+
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL;
+
+
+-- name: DeleteStaleRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at < NOW() - make_interval(secs => sqlc.arg('retention_seconds')::float8)
+   OR (
+       revoked_at < NOW() - make_interval(secs => sqlc.arg('retention_seconds')::float8)
+       AND NOT EXISTS (
+           SELECT 1 FROM refresh_tokens AS live
+           WHERE live.family_id = refresh_tokens.family_id
+             AND live.revoked_at IS NULL
+             AND live.expires_at > NOW()
+       )
+   );
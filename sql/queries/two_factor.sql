@@ -0,0 +1,77 @@
+-- name: SetTOTPSecret :one
+UPDATE users
+SET totp_secret = $2,
+    updated_at = NOW()
+WHERE id = $1
+  AND totp_enabled_at IS NULL
+RETURNING *;
+
+-- name: EnableTOTP :one
+UPDATE users
+SET totp_enabled_at = NOW(),
+    totp_last_step = $2,
+    updated_at = NOW()
+WHERE id = $1
+  AND totp_secret IS NOT NULL
+  AND totp_enabled_at IS NULL
+RETURNING *;
+
+-- name: UpdateTOTPLastStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1
+  AND totp_last_step < $2;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes(id, user_id, code_hash, created_at, used_at)
+SELECT gen_random_uuid(), sqlc.arg('user_id')::uuid, code_hash, NOW(), NULL
+FROM UNNEST(sqlc.arg('code_hashes')::text[]) AS code_hash;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: ListUnusedRecoveryCodes :many
+SELECT * FROM recovery_codes
+WHERE user_id = $1
+  AND used_at IS NULL;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE id = $1
+  AND used_at IS NULL;
+
+-- name: CreateLoginChallenge :one
+INSERT INTO login_challenges(token_hash, user_id, created_at, expires_at, used_at, attempts)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    NOW() + INTERVAL '5 minutes',
+    NULL,
+    0
+)
+RETURNING *;
+
+-- name: GetLoginChallenge :one
+SELECT * FROM login_challenges
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > NOW()
+  AND attempts < 5;
+
+-- name: RecordLoginChallengeAttempt :exec
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE token_hash = $1;
+
+-- name: UseLoginChallenge :execrows
+UPDATE login_challenges
+SET used_at = NOW()
+WHERE token_hash = $1
+  AND used_at IS NULL;
+
+-- name: CountTOTPEnabledUsers :one
+SELECT COUNT(*) FROM users
+WHERE totp_enabled_at IS NOT NULL;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- An expression index keeps the tsvector out of the chirps rows, queries have
+-- to use the same to_tsvector('english', body) expression to hit it
+CREATE INDEX chirps_body_search_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirps_body_search_idx;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE banned_words(
+    word TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO banned_words(word, created_at)
+VALUES
+    ('kerfuffle', NOW()),
+    ('sharbert', NOW()),
+    ('fornax', NOW());
+
+-- +goose Down
+DROP TABLE banned_words;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN parent_id UUID NULL REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_parent_id_created_at_idx ON chirps(parent_id, created_at);
+
+-- +goose Down
+DROP INDEX chirps_parent_id_created_at_idx;
+
+ALTER TABLE chirps
+DROP COLUMN parent_id;
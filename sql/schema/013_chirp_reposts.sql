@@ -0,0 +1,15 @@
+-- +goose Up
+-- is_repost outlives repost_of, so a repost whose original was deleted is shown
+-- as such instead of turning into a standalone chirp
+ALTER TABLE chirps
+ADD COLUMN repost_of UUID NULL REFERENCES chirps(id) ON DELETE SET NULL,
+ADD COLUMN is_repost BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX chirps_repost_of_idx ON chirps(repost_of);
+
+-- +goose Down
+DROP INDEX chirps_repost_of_idx;
+
+ALTER TABLE chirps
+DROP COLUMN is_repost,
+DROP COLUMN repost_of;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE login_failures(
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    previous_failure_at TIMESTAMP NULL
+);
+
+CREATE INDEX login_failures_last_failure_at_idx ON login_failures(last_failure_at);
+
+CREATE TABLE audit_log(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    event TEXT NOT NULL,
+    user_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    ip_address TEXT NOT NULL,
+    details TEXT NOT NULL
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log(created_at);
+
+-- +goose Down
+DROP TABLE audit_log;
+
+DROP TABLE login_failures;
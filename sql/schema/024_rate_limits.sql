@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE rate_limit_buckets(
+    key TEXT PRIMARY KEY,
+    tat TIMESTAMP NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_tat_idx ON rate_limit_buckets(tat);
+
+-- +goose Down
+DROP TABLE rate_limit_buckets;
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flogit2161/Chirpy/internal/chirptext"
+	"github.com/flogit2161/Chirpy/internal/database"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	trendingTagsLimit     = 10
+)
+
+type trendingTag struct {
+	Tag  string `json:"tag"`
+	Uses int64  `json:"uses"`
+}
+
+type trendingResponse struct {
+	Window string        `json:"window"`
+	Tags   []trendingTag `json:"tags"`
+}
+
+func (cfg *apiConfig) handlerRetrieveTagChirps(w http.ResponseWriter, r *http.Request) {
+	viewerID := cfg.optionalViewer(r)
+
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if !chirptext.ValidHashtag(tag) {
+		respondWithError(w, 400, "Tag must be letters, digits or underscores")
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.params()
+
+	chirps, err := cfg.db.RetrieveChirpsByHashtag(r.Context(), database.RetrieveChirpsByHashtagParams{
+		Tag:             tag,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error retrieving the tag's chirps")
+		return
+	}
+
+	page, err := cfg.chirpsPageFromRows(r.Context(), chirps, limit, viewerID)
+	if err != nil {
+		respondWithError(w, 500, "Error loading the tag's chirps")
+		return
+	}
+
+	respondWithJSON(w, 200, page)
+}
+
+func (cfg *apiConfig) handlerRetrieveTrendingTags(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if rawWindow := r.URL.Query().Get("window"); rawWindow != "" {
+		parsedWindow, err := time.ParseDuration(rawWindow)
+		if err != nil || parsedWindow <= 0 || parsedWindow > maxTrendingWindow {
+			respondWithError(w, 400, "Window must be a duration between 1s and 168h, e.g. 24h")
+			return
+		}
+		window = parsedWindow
+	}
+
+	tags, err := cfg.db.RetrieveTrendingHashtags(r.Context(), database.RetrieveTrendingHashtagsParams{
+		WindowSeconds: window.Seconds(),
+		RowLimit:      trendingTagsLimit,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error retrieving the trending tags")
+		return
+	}
+
+	response := trendingResponse{
+		Window: window.String(),
+		Tags:   []trendingTag{},
+	}
+	for _, tag := range tags {
+		response.Tags = append(response.Tags, trendingTag{Tag: tag.Name, Uses: tag.Uses})
+	}
+
+	respondWithJSON(w, 200, response)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+)
+
+// respondWithTokenError answers 401 with the reason the access token was refused,
+// repeated in the WWW-Authenticate header as described in RFC 6750.
+func respondWithTokenError(w http.ResponseWriter, err error) error {
+	msg := "Error validating token, token is not valid anymore"
+	switch {
+	case errors.Is(err, auth.ErrTokenExpired):
+		msg = "Token has expired, please refresh it"
+	case errors.Is(err, auth.ErrTokenNotYetValid):
+		msg = "Token is not valid yet"
+	case errors.Is(err, auth.ErrTokenSignatureInvalid),
+		errors.Is(err, auth.ErrTokenAlgorithm),
+		errors.Is(err, auth.ErrTokenUnknownKey):
+		msg = "Token signature is invalid"
+	case errors.Is(err, auth.ErrTokenIssuer), errors.Is(err, auth.ErrTokenAudience):
+		msg = "Token was not issued for this API"
+	case errors.Is(err, auth.ErrTokenMalformed), errors.Is(err, auth.ErrTokenClaimMissing):
+		msg = "Token is malformed"
+	}
+
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, msg))
+	return respondWithError(w, 401, msg)
+}
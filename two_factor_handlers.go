@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer        = "Chirpy"
+	recoveryCodeCount = 10
+)
+
+type twoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type loginChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+func (cfg *apiConfig) handlerSetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	if cfg.totpKey == nil {
+		respondWithError(w, 503, "Two-factor authentication is not configured on this server")
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, 404, "User can't be found")
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, 500, "Error generating the two-factor secret")
+		return
+	}
+
+	encryptedSecret, err := auth.EncryptSecret(cfg.totpKey, secret)
+	if err != nil {
+		respondWithError(w, 500, "Error encrypting the two-factor secret")
+		return
+	}
+
+	// Calling setup again before confirming simply replaces the pending secret
+	_, err = cfg.db.SetTOTPSecret(r.Context(), database.SetTOTPSecretParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: encryptedSecret, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Error saving the two-factor secret")
+		return
+	}
+
+	account := user.Email
+	if user.Username.Valid {
+		account = user.Username.String
+	}
+
+	respondWithJSON(w, 200, twoFactorSetupResponse{
+		Secret:     secret,
+		OtpauthURI: auth.TOTPURI(secret, totpIssuer, account),
+	})
+}
+
+func (cfg *apiConfig) handlerConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	params := parameters{}
+
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Error decoding the request")
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, 404, "User can't be found")
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+
+	if !user.TotpSecret.Valid {
+		respondWithError(w, 400, "Start the two-factor setup before confirming it")
+		return
+	}
+
+	step, ok, err := cfg.checkTOTPCode(user, params.Code)
+	if err != nil {
+		respondWithError(w, 500, "Error reading the two-factor secret")
+		return
+	}
+
+	if !ok {
+		respondWithError(w, 400, "Two-factor code is not valid")
+		return
+	}
+
+	recoveryCodes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, 500, "Error generating the recovery codes")
+		return
+	}
+
+	codeHashes := []string{}
+	for _, code := range recoveryCodes {
+		codeHash, err := auth.HashPassword(code)
+		if err != nil {
+			respondWithError(w, 500, "Error hashing the recovery codes")
+			return
+		}
+		codeHashes = append(codeHashes, codeHash)
+	}
+
+	// Enabling first locks the user's row, a concurrent confirmation waits for it
+	// and then finds 2FA enabled, so only one set of recovery codes is ever saved
+	tx, err := cfg.sqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Error enabling two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// The confirmation code counts as used so it can't also log in
+	_, err = qtx.EnableTOTP(r.Context(), database.EnableTOTPParams{
+		ID:           user.ID,
+		TotpLastStep: step,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Error enabling two-factor authentication")
+		return
+	}
+
+	err = qtx.DeleteRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Error saving the recovery codes")
+		return
+	}
+
+	err = qtx.CreateRecoveryCodes(r.Context(), database.CreateRecoveryCodesParams{
+		UserID:     user.ID,
+		CodeHashes: codeHashes,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error saving the recovery codes")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Error enabling two-factor authentication")
+		return
+	}
+
+	// Recovery codes are only ever shown once, they are stored hashed
+	respondWithJSON(w, 200, recoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// respondWithLoginChallenge answers the password step of a 2FA login.
+func (cfg *apiConfig) respondWithLoginChallenge(w http.ResponseWriter, r *http.Request, user database.User) {
+	challengeToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, 500, "Unable to create the login challenge")
+		return
+	}
+
+	challenge, err := cfg.db.CreateLoginChallenge(r.Context(), database.CreateLoginChallengeParams{
+		TokenHash: auth.HashRefreshToken(challengeToken),
+		UserID:    user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Unable to create the login challenge")
+		return
+	}
+
+	respondWithJSON(w, 202, loginChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+		ExpiresAt:         challenge.ExpiresAt,
+	})
+}
+
+func (cfg *apiConfig) handlerLogInTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	params := parameters{}
+
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Error decoding the request")
+		return
+	}
+
+	if params.ChallengeToken == "" || (params.Code == "" && params.RecoveryCode == "") {
+		respondWithError(w, 400, "Challenge token and a code or a recovery code are required")
+		return
+	}
+
+	challengeHash := auth.HashRefreshToken(params.ChallengeToken)
+	challenge, err := cfg.db.GetLoginChallenge(r.Context(), challengeHash)
+	if err != nil {
+		respondWithError(w, 401, "Login challenge is either expired, already used or does not exist")
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), challenge.UserID)
+	if err != nil {
+		respondWithError(w, 401, "Login challenge is either expired, already used or does not exist")
+		return
+	}
+
+	accountKey, ipKey := loginKeys(r, user.Email)
+	attempt, err := cfg.beginLoginAttempt(r.Context(), accountKey, ipKey)
+	if err != nil {
+		respondWithError(w, 500, "Error checking previous login attempts")
+		return
+	}
+
+	if attempt.retryAfter > 0 {
+		respondWithTooManyAttempts(w, attempt.retryAfter)
+		return
+	}
+
+	// Recovery codes are hashed, only TOTP codes need the encryption key
+	if params.Code != "" && cfg.totpKey == nil {
+		respondWithError(w, 503, "Two-factor codes can't be checked, TOTP_ENCRYPTION_KEY is not configured on this server")
+		return
+	}
+
+	var ok bool
+	if params.Code != "" {
+		ok, err = cfg.useTOTPCode(r.Context(), user, params.Code)
+	} else {
+		ok, err = cfg.useRecoveryCode(r.Context(), user, params.RecoveryCode)
+	}
+	if err != nil {
+		respondWithError(w, 500, "Error checking the two-factor code")
+		return
+	}
+
+	if !ok {
+		// A challenge only allows a few guesses, then the password has to be entered again
+		err = cfg.db.RecordLoginChallengeAttempt(r.Context(), challengeHash)
+		if err != nil {
+			respondWithError(w, 500, "Error recording the failed attempt")
+			return
+		}
+		cfg.loginAttemptFailed(r, attempt, uuid.NullUUID{UUID: user.ID, Valid: true})
+		respondWithError(w, 401, "Two-factor code is not valid")
+		return
+	}
+
+	used, err := cfg.db.UseLoginChallenge(r.Context(), challengeHash)
+	if err != nil {
+		respondWithError(w, 500, "Error completing the login challenge")
+		return
+	}
+
+	if used == 0 {
+		respondWithError(w, 401, "Login challenge is either expired, already used or does not exist")
+		return
+	}
+
+	cfg.loginAttemptSucceeded(r.Context(), attempt)
+	cfg.respondWithSession(w, r, user)
+}
+
+// checkTOTPCode validates code against the user's secret and returns its time step.
+func (cfg *apiConfig) checkTOTPCode(user database.User, code string) (int64, bool, error) {
+	if cfg.totpKey == nil || !user.TotpSecret.Valid {
+		return 0, false, errors.New("Two-factor secret is not available")
+	}
+
+	secret, err := auth.DecryptSecret(cfg.totpKey, user.TotpSecret.String)
+	if err != nil {
+		return 0, false, err
+	}
+
+	step, ok := auth.ValidateTOTP(code, secret, time.Now())
+	return step, ok, nil
+}
+
+// useTOTPCode accepts each code once, a replayed code fails even inside its period.
+func (cfg *apiConfig) useTOTPCode(ctx context.Context, user database.User, code string) (bool, error) {
+	step, ok, err := cfg.checkTOTPCode(user, code)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	updated, err := cfg.db.UpdateTOTPLastStep(ctx, database.UpdateTOTPLastStepParams{
+		ID:           user.ID,
+		TotpLastStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated == 1, nil
+}
+
+func (cfg *apiConfig) useRecoveryCode(ctx context.Context, user database.User, code string) (bool, error) {
+	recoveryCodes, err := cfg.db.ListUnusedRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	code = auth.NormalizeRecoveryCode(code)
+	for _, recoveryCode := range recoveryCodes {
+		match, err := auth.CheckPasswordHash(code, recoveryCode.CodeHash)
+		if err != nil {
+			return false, err
+		}
+		if !match {
+			continue
+		}
+
+		used, err := cfg.db.UseRecoveryCode(ctx, recoveryCode.ID)
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+	return false, nil
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/chirptext"
 	"github.com/flogit2161/Chirpy/internal/database"
 	"github.com/google/uuid"
 )
@@ -36,6 +40,20 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !validEmail(user.Email) {
+		respondWithError(w, 400, "Email is not a valid address")
+		return
+	}
+
+	if !chirptext.ValidUsername(user.Username) {
+		respondWithError(w, 400, "Username must be 3 to 20 letters, digits or underscores")
+		return
+	}
+
+	if !cfg.checkPassword(w, user.Password, user.Email) {
+		return
+	}
+
 	hashedPassword, err := auth.HashPassword(user.Password)
 	if err != nil {
 		respondWithError(w, 500, "Error hashing the users password")
@@ -45,21 +63,25 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 	createdUser, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          user.Email,
 		HashedPassword: hashedPassword,
+		Username:       sql.NullString{String: user.Username, Valid: true},
 	})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Email or username is already taken")
+		return
+	}
 	if err != nil {
 		respondWithError(w, 500, "Error creating user")
 		return
 	}
-	// Map database.User to main.User to send back JSON
-	user = User{
-		ID:        createdUser.ID,
-		CreatedAt: createdUser.CreatedAt,
-		UpdatedAt: createdUser.UpdatedAt,
-		Email:     createdUser.Email,
-		RedChirpy: createdUser.IsChirpyRed,
+
+	// The account is kept even if the verification email could not be sent
+	err = cfg.sendVerificationEmail(r.Context(), createdUser)
+	if err != nil {
+		log.Printf("Could not send verification email to user %v, err :%v", createdUser.ID, err)
 	}
 
-	respondWithJSON(w, 201, user)
+	// Map database.User to main.User to send back JSON
+	respondWithJSON(w, 201, databaseUserToJSON(createdUser))
 }
 
 func (cfg *apiConfig) handlerLogIn(w http.ResponseWriter, r *http.Request) {
@@ -79,8 +101,21 @@ func (cfg *apiConfig) handlerLogIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	accountKey, ipKey := loginKeys(r, logs.Email)
+	attempt, err := cfg.beginLoginAttempt(r.Context(), accountKey, ipKey)
+	if err != nil {
+		respondWithError(w, 500, "Error checking previous login attempts")
+		return
+	}
+
+	if attempt.retryAfter > 0 {
+		respondWithTooManyAttempts(w, attempt.retryAfter)
+		return
+	}
+
 	userLogs, err := cfg.db.GetUserByEmail(r.Context(), logs.Email)
 	if err != nil {
+		cfg.loginAttemptFailed(r, attempt, uuid.NullUUID{})
 		respondWithError(w, 401, "Error accessing user via email, please create user before logging in")
 		return
 	}
@@ -92,11 +127,59 @@ func (cfg *apiConfig) handlerLogIn(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !match {
+		cfg.loginAttemptFailed(r, attempt, uuid.NullUUID{UUID: userLogs.ID, Valid: true})
 		respondWithError(w, 401, "Password doesnt match")
 		return
 	}
 
-	token, err := auth.MakeJWT(userLogs.ID, cfg.jwt, 1*time.Hour)
+	cfg.loginAttemptSucceeded(r.Context(), attempt)
+
+	cfg.rehashPasswordIfNeeded(r.Context(), userLogs, logs.Password)
+
+	// With 2FA on, the password only buys a challenge to exchange with a code at /api/login/2fa
+	if userLogs.TotpEnabledAt.Valid {
+		cfg.respondWithLoginChallenge(w, r, userLogs)
+		return
+	}
+
+	cfg.respondWithSession(w, r, userLogs)
+}
+
+// rehashPasswordIfNeeded upgrades a hash made with older argon2id costs while the
+// plaintext password is at hand, so the user base migrates as people log in.
+func (cfg *apiConfig) rehashPasswordIfNeeded(ctx context.Context, user database.User, password string) {
+	rehash, err := auth.NeedsRehash(user.HashedPassword)
+	if err != nil || !rehash {
+		return
+	}
+
+	newHash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("Could not rehash password of user %v, err :%v", user.ID, err)
+		return
+	}
+
+	// Only replaces the hash that was checked, a concurrent password change wins
+	err = cfg.db.RehashUserPassword(ctx, database.RehashUserPasswordParams{
+		NewHash: newHash,
+		ID:      user.ID,
+		OldHash: user.HashedPassword,
+	})
+	if err != nil {
+		log.Printf("Could not save rehashed password of user %v, err :%v", user.ID, err)
+	}
+}
+
+// respondWithSession logs the user in: a fresh access token and a new refresh token family.
+func (cfg *apiConfig) respondWithSession(w http.ResponseWriter, r *http.Request, userLogs database.User) {
+	// Failures are only forgotten once the whole login, 2FA included, succeeded
+	accountKey, _ := loginKeys(r, userLogs.Email)
+	err := cfg.accountGuard.Succeed(r.Context(), accountKey)
+	if err != nil {
+		log.Printf("Could not reset failed logins for %v, err :%v", accountKey, err)
+	}
+
+	token, err := cfg.jwt.MakeJWT(userLogs.ID, 1*time.Hour)
 	if err != nil {
 		respondWithError(w, 500, "Unable to create token for user")
 		return
@@ -111,8 +194,10 @@ func (cfg *apiConfig) handlerLogIn(w http.ResponseWriter, r *http.Request) {
 	_, err = cfg.db.GenerateRefreshToken(
 		r.Context(),
 		database.GenerateRefreshTokenParams{
-			Token:  encodedRefreshToken,
-			UserID: userLogs.ID,
+			TokenHash: auth.HashRefreshToken(encodedRefreshToken),
+			UserID:    userLogs.ID,
+			UserAgent: clientUserAgent(r),
+			IpAddress: clientIP(r),
 		},
 	)
 	if err != nil {
@@ -120,17 +205,10 @@ func (cfg *apiConfig) handlerLogIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jsonUser := User{
-		ID:           userLogs.ID,
-		CreatedAt:    userLogs.CreatedAt,
-		UpdatedAt:    userLogs.UpdatedAt,
-		Email:        userLogs.Email,
-		Token:        token,
-		RefreshToken: encodedRefreshToken,
-		RedChirpy:    userLogs.IsChirpyRed,
-	}
+	jsonUser := databaseUserToJSON(userLogs)
+	jsonUser.Token = token
+	jsonUser.RefreshToken = encodedRefreshToken
 	respondWithJSON(w, 200, jsonUser)
-
 }
 
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
@@ -140,29 +218,63 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userByToken, err := cfg.db.GetUserFromRefreshToken(r.Context(), bearerToken)
+	encodedRefreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
+		respondWithError(w, 500, "Unable to create encoded string refresh token")
+		return
+	}
+
+	// Each refresh token can only be used once, it is swapped for a new one of the same family
+	rotatedToken, err := cfg.db.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		OldTokenHash: auth.HashRefreshToken(bearerToken),
+		NewTokenHash: auth.HashRefreshToken(encodedRefreshToken),
+		UserAgent:    clientUserAgent(r),
+		IpAddress:    clientIP(r),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.detectRefreshTokenReuse(r.Context(), auth.HashRefreshToken(bearerToken))
 		respondWithError(w, 401, "Could not acces user via refresh token, token does not exist")
 		return
 	}
+	if err != nil {
+		respondWithError(w, 500, "Unable to rotate refresh token")
+		return
+	}
 
-	newJWTToken, err := auth.MakeJWT(userByToken.ID, cfg.jwt, 1*time.Hour)
+	newJWTToken, err := cfg.jwt.MakeJWT(rotatedToken.UserID, 1*time.Hour)
 	if err != nil {
 		respondWithError(w, 500, "Could not re-create JWT Token for user")
 		return
 	}
 
 	type tokenResponse struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	tokenResp := tokenResponse{
-		Token: newJWTToken,
+		Token:        newJWTToken,
+		RefreshToken: encodedRefreshToken,
 	}
 
 	respondWithJSON(w, 200, tokenResp)
 }
 
+// detectRefreshTokenReuse revokes the whole family when an already rotated token
+// comes back, since either the client or an attacker holds a stolen copy.
+func (cfg *apiConfig) detectRefreshTokenReuse(ctx context.Context, tokenHash string) {
+	storedToken, err := cfg.db.GetRefreshToken(ctx, tokenHash)
+	if err != nil || !storedToken.RotatedAt.Valid {
+		return
+	}
+
+	log.Printf("Rotated refresh token reused for user %v, revoking token family %v", storedToken.UserID, storedToken.FamilyID)
+	err = cfg.db.RevokeRefreshTokenFamily(ctx, storedToken.FamilyID)
+	if err != nil {
+		log.Printf("Could not revoke token family %v, err :%v", storedToken.FamilyID, err)
+	}
+}
+
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 	bearerToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -170,7 +282,7 @@ func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.db.RevokeToken(r.Context(), bearerToken)
+	err = cfg.db.RevokeToken(r.Context(), auth.HashRefreshToken(bearerToken))
 	if err != nil {
 		respondWithError(w, 401, "Unable to revoke refresh token")
 		return
@@ -186,15 +298,19 @@ func (cfg *apiConfig) handlerUpdateUserLogs(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	userUUID, err := auth.ValidateJWT(bearerToken, cfg.jwt)
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
 	if err != nil {
-		respondWithError(w, 401, "Error validating token, token is not valid anymore")
+		respondWithTokenError(w, err)
 		return
 	}
 
 	type loginParams struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email       string  `json:"email"`
+		Password    string  `json:"password"`
+		Username    *string `json:"username"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -208,34 +324,140 @@ func (cfg *apiConfig) handlerUpdateUserLogs(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(logs.Password)
+	err = validateProfile(logs.DisplayName, logs.Bio, logs.AvatarURL)
 	if err != nil {
-		respondWithError(w, 500, "Error hashing the password")
+		respondWithError(w, 400, err.Error())
 		return
 	}
 
-	newUserLogs, err := cfg.db.UpdateLogInParams(
-		r.Context(),
-		database.UpdateLogInParamsParams{
-			ID:             userUUID,
-			Email:          logs.Email,
-			HashedPassword: hashedPassword,
-		},
-	)
+	if logs.Username != nil && !chirptext.ValidUsername(*logs.Username) {
+		respondWithError(w, 400, "Username must be 3 to 20 letters, digits or underscores")
+		return
+	}
+
+	newUserLogs, err := cfg.db.GetUser(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, 404, "User can't be found")
+		return
+	}
+
+	// Email and password are only replaced together, a profile-only update leaves them alone
+	updateLogIn := logs.Email != "" || logs.Password != ""
+	hashedPassword := ""
+	if updateLogIn {
+		if logs.Email == "" || logs.Password == "" {
+			respondWithError(w, 400, "Email and password must be updated together")
+			return
+		}
+
+		if !validEmail(logs.Email) {
+			respondWithError(w, 400, "Email is not a valid address")
+			return
+		}
+
+		if !cfg.checkPassword(w, logs.Password, logs.Email) {
+			return
+		}
+
+		hashedPassword, err = auth.HashPassword(logs.Password)
+		if err != nil {
+			respondWithError(w, 500, "Error hashing the password")
+			return
+		}
+	}
+	previousEmail := newUserLogs.Email
+
+	// Nothing is saved unless every part of the update is, a taken username can't leave a new password behind
+	tx, err := cfg.sqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Error updating the user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if updateLogIn {
+		newUserLogs, err = qtx.UpdateLogInParams(
+			r.Context(),
+			database.UpdateLogInParamsParams{
+				ID:             userUUID,
+				Email:          logs.Email,
+				HashedPassword: hashedPassword,
+			},
+		)
+		if isUniqueViolation(err) {
+			respondWithError(w, 409, "Email is already taken")
+			return
+		}
+		if err != nil {
+			respondWithError(w, 500, "Error assigning new parameters to the user")
+			return
+		}
+
+		// A new email address has to be verified again, links mailed to the old one stop working
+		if newUserLogs.Email != previousEmail {
+			err = qtx.DeletePendingEmailVerificationTokens(r.Context(), userUUID)
+			if err != nil {
+				respondWithError(w, 500, "Error revoking the pending verification links")
+				return
+			}
+
+			err = qtx.DeletePendingPasswordResetTokens(r.Context(), userUUID)
+			if err != nil {
+				respondWithError(w, 500, "Error revoking the pending password resets")
+				return
+			}
+		}
+	}
+
+	if logs.Username != nil {
+		newUserLogs, err = qtx.UpdateUsername(
+			r.Context(),
+			database.UpdateUsernameParams{
+				ID:       userUUID,
+				Username: sql.NullString{String: *logs.Username, Valid: true},
+			},
+		)
+		if isUniqueViolation(err) {
+			respondWithError(w, 409, "Username is already taken")
+			return
+		}
+		if err != nil {
+			respondWithError(w, 500, "Error updating the username")
+			return
+		}
+	}
+
+	if logs.DisplayName != nil || logs.Bio != nil || logs.AvatarURL != nil {
+		newUserLogs, err = qtx.UpdateUserProfile(
+			r.Context(),
+			database.UpdateUserProfileParams{
+				DisplayName: optionalString(logs.DisplayName),
+				Bio:         optionalString(logs.Bio),
+				AvatarUrl:   optionalString(logs.AvatarURL),
+				ID:          userUUID,
+			},
+		)
+		if err != nil {
+			respondWithError(w, 500, "Error updating the user's profile")
+			return
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
-		respondWithError(w, 500, "Error assigning new parameters to the user")
+		respondWithError(w, 500, "Error updating the user")
 		return
 	}
 
-	newUser := User{
-		ID:        userUUID,
-		CreatedAt: newUserLogs.CreatedAt,
-		UpdatedAt: newUserLogs.UpdatedAt,
-		Email:     newUserLogs.Email,
-		RedChirpy: newUserLogs.IsChirpyRed,
+	if newUserLogs.Email != previousEmail {
+		err = cfg.sendVerificationEmail(r.Context(), newUserLogs)
+		if err != nil {
+			log.Printf("Could not send verification email to user %v, err :%v", newUserLogs.ID, err)
+		}
 	}
 
-	respondWithJSON(w, 200, newUser)
+	respondWithJSON(w, 200, databaseUserToJSON(newUserLogs))
 }
 
 func (cfg *apiConfig) handlerUpgradeRedChirpy(w http.ResponseWriter, r *http.Request) {
@@ -287,3 +509,87 @@ func (cfg *apiConfig) handlerUpgradeRedChirpy(w http.ResponseWriter, r *http.Req
 
 	w.WriteHeader(204)
 }
+
+func (cfg *apiConfig) handlerRetrieveMe(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, 404, "User can't be found")
+		return
+	}
+
+	respondWithJSON(w, 200, databaseUserToJSON(user))
+}
+
+func (cfg *apiConfig) handlerRetrieveUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Error parsing user ID into a UUID")
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "User can't be found")
+		return
+	}
+
+	respondWithJSON(w, 200, databaseUserToPublicJSON(user))
+}
+
+func (cfg *apiConfig) handlerRetrieveMyMentions(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Token is either expired or does not exist")
+		return
+	}
+
+	userUUID, err := cfg.jwt.ValidateJWT(bearerToken)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.params()
+
+	chirps, err := cfg.db.RetrieveMentions(r.Context(), database.RetrieveMentionsParams{
+		UserID:          userUUID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Error retrieving the mentions")
+		return
+	}
+
+	page, err := cfg.chirpsPageFromRows(r.Context(), chirps, limit, uuid.NullUUID{UUID: userUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "Error loading the mentions")
+		return
+	}
+
+	respondWithJSON(w, 200, page)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/flogit2161/Chirpy/internal/database"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+// databaseUserToJSON is the view a user gets of their own account.
+func databaseUserToJSON(user database.User) User {
+	return User{
+		ID:               user.ID,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt.Valid,
+		TwoFactorEnabled: user.TotpEnabledAt.Valid,
+		Username:         user.Username.String,
+		RedChirpy:        user.IsChirpyRed,
+		DisplayName:      user.DisplayName,
+		Bio:              user.Bio,
+		AvatarURL:        user.AvatarUrl,
+	}
+}
+
+// databaseUserToPublicJSON is the view anyone else gets, without the email.
+func databaseUserToPublicJSON(user database.User) User {
+	jsonUser := databaseUserToJSON(user)
+	jsonUser.Email = ""
+	jsonUser.TwoFactorEnabled = false
+	return jsonUser
+}
+
+// validEmail only accepts a bare address, without a display name.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+func validateProfile(displayName, bio, avatarURL *string) error {
+	if displayName != nil && utf8.RuneCountInString(*displayName) > maxDisplayNameLength {
+		return fmt.Errorf("Display name can't be longer than %d characters", maxDisplayNameLength)
+	}
+
+	if bio != nil && utf8.RuneCountInString(*bio) > maxBioLength {
+		return fmt.Errorf("Bio can't be longer than %d characters", maxBioLength)
+	}
+
+	// An empty avatar URL clears the avatar
+	if avatarURL != nil && *avatarURL != "" {
+		parsed, err := url.Parse(*avatarURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("Avatar URL must be an http or https URL")
+		}
+	}
+
+	return nil
+}
+
+func optionalString(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *value, Valid: true}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/flogit2161/Chirpy/internal/auth"
+	"github.com/flogit2161/Chirpy/internal/database"
+	"github.com/flogit2161/Chirpy/internal/mailer"
+)
+
+// sendVerificationEmail issues a single-use token valid for 24 hours and mails its link.
+// Only the token's hash is stored, like refresh tokens, along with the address it
+// verifies so it is worthless once the user's email changes.
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashRefreshToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+	})
+	if err != nil {
+		return fmt.Errorf("Error saving the verification token, err :%v", err)
+	}
+
+	link := cfg.baseURL + "/api/users/verify?token=" + url.QueryEscape(token)
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email",
+		Body:    "Welcome to Chirpy! Open this link within 24 hours to verify your email:\n\n" + link + "\n",
+	})
+}
+
+func (cfg *apiConfig) handlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithError(w, 400, "Verification token is missing")
+		return
+	}
+
+	user, err := cfg.db.VerifyEmailWithToken(r.Context(), auth.HashRefreshToken(token))
+	if err != nil {
+		respondWithError(w, 400, "Verification token is either expired, already used or does not exist")
+		return
+	}
+
+	respondWithJSON(w, 200, databaseUserToJSON(user))
+}